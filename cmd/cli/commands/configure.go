@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"idasen-desk/internal/blue"
 	"idasen-desk/internal/config"
@@ -8,18 +9,13 @@ import (
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
-	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 	"tinygo.org/x/bluetooth"
 )
 
-func handleSelectionOfDevice(o *bluetooth.ScanResult, c *config.Configuration, path string) {
+func handleSelectionOfDevice(o *bluetooth.ScanResult, c *config.Configuration) {
 	c.ConnectionAddress = o.Address.String()
 	c.LocalName = o.LocalName()
-
-	if err := c.Save(path); err != nil {
-		log.Fatal(err)
-	}
 }
 
 func HeaderPrimitive(c *config.Configuration) tview.Primitive {
@@ -38,7 +34,7 @@ func HeaderPrimitive(c *config.Configuration) tview.Primitive {
 }
 
 func Configure(_ *cli.Context, args InputFlags) (err error) {
-	configuration, err := config.Load(args.ConfigPath)
+	configuration, err := loadConfig(args)
 	if err != nil {
 		return err
 	}
@@ -65,6 +61,8 @@ func Configure(_ *cli.Context, args InputFlags) (err error) {
 
 	var scanResults []*bluetooth.ScanResult
 	app := tview.NewApplication()
+	wizard := newHeightWizard(app, configuration, args)
+	selected := false
 
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Key() {
@@ -87,20 +85,33 @@ func Configure(_ *cli.Context, args InputFlags) (err error) {
 			}
 
 			list.AddItem(name, "", rune(96+len(scanResults)), func() {
+				// Only the first selection counts, the list stays visible
+				// while the wizard starts.
+				if selected {
+					return
+				}
+
 				handleSelectionOfDevice(
 					scanResults[list.GetCurrentItem()],
 					configuration,
-					args.ConfigPath,
 				)
-				defer close(done)
-				defer app.Stop()
+
+				// Continue with capturing the sit and stand heights, the
+				// scan has to be stopped before connecting to the desk.
+				selected = true
+				close(done)
+				go wizard.Start()
 			})
 
 			app.Draw()
 		}
 	}()
 
-	return app.SetRoot(grid, true).
-		EnableMouse(false).
-		Run()
+	err = app.SetRoot(grid, true).EnableMouse(false).Run()
+	if !selected {
+		return err
+	}
+
+	wizard.Close()
+	return errors.Join(err, wizard.Err())
 }
@@ -0,0 +1,272 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"idasen-desk/internal/config"
+	"idasen-desk/internal/desk"
+	"idasen-desk/internal/duty"
+	"idasen-desk/internal/guard"
+	"idasen-desk/internal/history"
+	"idasen-desk/internal/hooks"
+	"idasen-desk/internal/lock"
+	"idasen-desk/internal/route"
+	"idasen-desk/internal/webhook"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// webhookCloseTimeout is how long to wait for the webhooks to deliver the
+// queued events before exiting.
+const webhookCloseTimeout = 10 * time.Second
+
+// controller is the subset of the desk used by commands that can be routed
+// through another process holding the desk.
+type controller interface {
+	Name() string
+	GetHeight() (float64, error)
+	MoveToTarget(target float64) error
+	StopMove() error
+	Close() error
+}
+
+// session is a connection to the desk held by this process. The desk is locked
+// for the lifetime of the session and other processes can route their
+// requests through it.
+type session struct {
+	*desk.Desk
+
+	lock   *lock.Lock
+	server *route.Server
+	hooks  *hooks.Runner
+	sender *webhook.Sender
+}
+
+// Close disconnects from the desk and releases the lock, allowing other
+// processes to connect to it.
+func (s *session) Close() error {
+	var errs []error
+
+	if s.server != nil {
+		errs = append(errs, s.server.Close())
+	}
+
+	errs = append(errs, s.Desk.Close())
+
+	// Hooks and webhooks are waited on after disconnecting, so the
+	// disconnected event is handled before exiting.
+	if s.hooks != nil {
+		s.hooks.Wait()
+	}
+
+	if s.sender != nil {
+		closeSender(s.sender)
+	}
+
+	errs = append(errs, s.lock.Release())
+	return errors.Join(errs...)
+}
+
+// loadConfig loads the configuration, applying the profile selected with the
+// profile flag, or the active profile of the configuration.
+func loadConfig(args InputFlags) (*config.Configuration, error) {
+	configuration, err := config.Load(args.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = configuration.ApplyProfile(args.Profile); err != nil {
+		return nil, err
+	}
+
+	return configuration, nil
+}
+
+// connectDesk locks and connects to the desk defined within the given
+// configuration, applying any configured desk options.
+func connectDesk(configuration *config.Configuration, args InputFlags) (*session, error) {
+	if configuration.ConnectionAddress == "" {
+		return nil, desk.ErrNotConfigured
+	}
+
+	deskLock, err := lock.Acquire(configuration.ConnectionAddress, lock.Mode(args.LockMode), args.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []desk.Option{
+		desk.WithMoveMode(desk.MoveMode(configuration.MoveMode)),
+		desk.WithReferenceMinFirmware(configuration.ReferenceMinFirmware),
+		desk.WithWakeUp(configuration.WakeUp),
+		desk.WithKeepAlive(configuration.KeepAlive),
+		desk.WithIdleRelease(configuration.IdleRelease),
+		desk.WithSoftLimits(configuration.MinHeight, configuration.MaxHeight),
+		desk.WithProfile(configuration.AppliedProfile()),
+		desk.WithGuard(guard.New(args.ConfigPath).Check),
+		desk.WithSource(commandSource()),
+		desk.WithSafetyPolicy(desk.SafetyPolicy{
+			Action:  desk.SafetyAction(configuration.Safety.Action),
+			Retries: configuration.Safety.Retries,
+			Delay:   configuration.Safety.Delay,
+		}),
+	}
+
+	if configuration.HistoryPath != "" {
+		recorder := history.New(configuration.HistoryPath)
+		opts = append(opts, desk.WithEventHandler(recorder.Record))
+
+		if cycle := dutyCycle(configuration, recorder); cycle != nil {
+			opts = append(opts, desk.WithMoveGuard(cycle.Check))
+		}
+	} else if configuration.DutyCycle.OnTime > 0 {
+		log.Warn("motor duty cycle protection requires the history, set history_path")
+	}
+
+	var runner *hooks.Runner
+	if len(configuration.Hooks) > 0 {
+		runner = hooks.New(configHooks(configuration.Hooks))
+		opts = append(opts, desk.WithEventHandler(runner.Handle))
+	}
+
+	var sender *webhook.Sender
+	if len(configuration.Webhooks) > 0 {
+		sender = webhook.New(configWebhooks(configuration.Webhooks), configuration.WebhookDeadLetter)
+		opts = append(opts, desk.WithEventHandler(sender.Handle))
+	}
+
+	d, err := desk.NewDesk(
+		configuration.LocalName,
+		configuration.ConnectionAddress,
+		true,
+		opts...,
+	)
+
+	if err != nil {
+		if sender != nil {
+			closeSender(sender)
+		}
+
+		return nil, errors.Join(
+			fmt.Errorf("failed to create new desk instance, %w", err),
+			deskLock.Release(),
+		)
+	}
+
+	s := &session{Desk: d, lock: deskLock, hooks: runner, sender: sender}
+
+	// Failing to accept routed requests does not stop this process from using
+	// the desk, others will have to wait instead.
+	if s.server, err = route.Serve(lock.SocketPath(configuration.ConnectionAddress), d); err != nil {
+		log.WithError(err).Warn("failed to accept routed desk requests")
+	}
+
+	return s, nil
+}
+
+// openController connects to the desk, or when configured to route and the
+// desk is held by another process, routes requests through that process.
+func openController(configuration *config.Configuration, args InputFlags) (controller, error) {
+	s, err := connectDesk(configuration, args)
+
+	var busyErr *lock.BusyError
+	if errors.As(err, &busyErr) && lock.Mode(args.LockMode) == lock.ModeRoute {
+		log.Infof("desk is in use by pid %d, routing request", busyErr.Owner.PID)
+
+		return route.NewClient(
+			lock.SocketPath(configuration.ConnectionAddress),
+			fmt.Sprintf("%s (via pid %d)", configuration.LocalName, busyErr.Owner.PID),
+			desk.Requester{
+				Source:    commandSource(),
+				Profile:   configuration.AppliedProfile(),
+				MinHeight: configuration.MinHeight,
+				MaxHeight: configuration.MaxHeight,
+			},
+		), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// configHooks converts the configured hooks to the hooks run on desk events.
+func configHooks(configured []config.Hook) []hooks.Hook {
+	converted := make([]hooks.Hook, 0, len(configured))
+
+	for _, hook := range configured {
+		events := make([]desk.EventType, 0, len(hook.Events))
+		for _, event := range hook.Events {
+			events = append(events, desk.EventType(event))
+		}
+
+		converted = append(converted, hooks.Hook{Events: events, Command: hook.Command})
+	}
+
+	return converted
+}
+
+// configWebhooks converts the configured webhooks to the webhooks desk events
+// are posted to.
+func configWebhooks(configured []config.Webhook) []webhook.Webhook {
+	converted := make([]webhook.Webhook, 0, len(configured))
+
+	for _, hook := range configured {
+		events := make([]desk.EventType, 0, len(hook.Events))
+		for _, event := range hook.Events {
+			events = append(events, desk.EventType(event))
+		}
+
+		converted = append(converted, webhook.Webhook{URL: hook.URL, Events: events, Secret: hook.Secret})
+	}
+
+	return converted
+}
+
+// dutyCycle returns the configured duty cycle of the motor, nil if the
+// protection is disabled.
+func dutyCycle(configuration *config.Configuration, recorder *history.History) *duty.Cycle {
+	if configuration.DutyCycle.OnTime <= 0 || configuration.DutyCycle.Window <= 0 {
+		return nil
+	}
+
+	return duty.New(
+		recorder,
+		configuration.DutyCycle.OnTime,
+		configuration.DutyCycle.Window,
+		duty.Action(configuration.DutyCycle.Action),
+	)
+}
+
+// commandSource describes the command being run, logged when a movement of
+// the desk is refused.
+func commandSource() string {
+	return fmt.Sprintf("%s (pid %d)", strings.Join(os.Args[1:], " "), os.Getpid())
+}
+
+// closeSender waits for the webhooks to deliver the queued events, for at most
+// webhookCloseTimeout or until interrupted, dead lettering those it gave up on.
+func closeSender(sender *webhook.Sender) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(ctx, webhookCloseTimeout)
+	defer cancel()
+
+	sender.Close(ctx)
+}
+
+// closeDesk disconnects from the desk at the end of a command, so the desk is
+// free for other devices such as the phone app.
+func closeDesk(d controller) {
+	if err := d.Close(); err != nil {
+		log.WithError(err).Warn("failed to disconnect from desk")
+	}
+}
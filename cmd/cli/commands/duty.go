@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"idasen-desk/internal/history"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// Duty reports the remaining duty cycle budget of the motor, from the
+// movement history.
+func Duty(_ *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	if configuration.HistoryPath == "" {
+		return fmt.Errorf("duty cycle protection requires the history, set history_path")
+	}
+
+	cycle := dutyCycle(configuration, history.New(configuration.HistoryPath))
+	if cycle == nil {
+		return fmt.Errorf("duty cycle protection is disabled, set duty_cycle.on_time and duty_cycle.window")
+	}
+
+	budget, err := cycle.Budget(time.Now())
+	if err != nil {
+		return err
+	}
+
+	if args.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(budget)
+	}
+
+	log.Printf("used: %s of %s in the last %s", budget.Used.Round(time.Second), budget.Limit, budget.Window)
+	log.Printf("remaining: %s", budget.Remaining.Round(time.Second))
+
+	if budget.Remaining == 0 {
+		log.Printf("available again at: %s", budget.Available.Format("15:04:05"))
+	}
+
+	return nil
+}
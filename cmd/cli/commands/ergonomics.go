@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"idasen-desk/internal/config"
+	"idasen-desk/internal/desk"
+	"idasen-desk/internal/ergonomics"
+	"math"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// Ergonomics recommends sit and stand heights from the body measurements of
+// the user, offering to save them as the sit and stand presets along with the
+// measurements.
+func Ergonomics(_ *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	body := configuration.Body
+
+	if args.BodyHeight != "" {
+		if body.Height, err = parseDistance(args.BodyHeight); err != nil {
+			return fmt.Errorf("invalid body height, %w", err)
+		}
+	}
+
+	if args.ElbowHeight != "" {
+		if body.ElbowHeight, err = parseDistance(args.ElbowHeight); err != nil {
+			return fmt.Errorf("invalid elbow height, %w", err)
+		}
+	}
+
+	recommendation, err := ergonomics.Recommend(ergonomics.Measurements{
+		Height:      body.Height,
+		ElbowHeight: body.ElbowHeight,
+	})
+
+	if err != nil {
+		return fmt.Errorf("%w, provide --body-height or --elbow-height", err)
+	}
+
+	if args.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err = encoder.Encode(recommendation); err != nil {
+			return err
+		}
+	} else {
+		log.Printf("sit height: %.2f (monitor top at %.2f)", recommendation.SitHeight, recommendation.SitMonitorHeight)
+		log.Printf("stand height: %.2f (monitor top at %.2f)", recommendation.StandHeight, recommendation.StandMonitorHeight)
+	}
+
+	if !args.Save && (args.JSON || !confirm("save as the sit and stand presets?")) {
+		return nil
+	}
+
+	configuration.Body = body
+	configuration.SitHeight = clampHeight(configuration, "sit", recommendation.SitHeight)
+	configuration.StandHeight = clampHeight(configuration, "stand", recommendation.StandHeight)
+
+	return configuration.Save(args.ConfigPath)
+}
+
+// clampHeight keeps a recommended height within the limits the desk is moved
+// to, the same limits as desk.MoveLimits: the soft limits of the configuration
+// within the limits of the IDÅSEN desk, as the desk is not connected to.
+func clampHeight(configuration *config.Configuration, name string, height float64) float64 {
+	minHeight, maxHeight := desk.MinHeight, desk.MaxHeight
+
+	if configuration.MinHeight != 0 {
+		minHeight = math.Max(minHeight, configuration.MinHeight)
+	}
+
+	if configuration.MaxHeight != 0 {
+		maxHeight = math.Min(maxHeight, configuration.MaxHeight)
+	}
+
+	// Rounded before clamping, so rounding cannot move it past a limit.
+	height = math.Round(height*100) / 100
+	clamped := math.Max(minHeight, math.Min(maxHeight, height))
+
+	if clamped != height {
+		log.Warnf("recommended %s height (%.2f) is outside the limits of the desk, using %.4f", name, height, clamped)
+	}
+
+	return clamped
+}
+
+// confirm asks the user a yes or no question on the terminal, defaulting to no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
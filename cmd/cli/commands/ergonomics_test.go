@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"idasen-desk/internal/config"
+	"idasen-desk/internal/desk"
+	"testing"
+)
+
+func TestClampHeight(t *testing.T) {
+	tests := []struct {
+		name          string
+		configuration config.Configuration
+		height        float64
+		expected      float64
+	}{
+		{"within limits", config.Configuration{}, 1.104, 1.10},
+		{"below the desk", config.Configuration{}, 0.50, desk.MinHeight},
+		{"above the desk", config.Configuration{}, 1.40, desk.MaxHeight},
+		{"above the soft limit", config.Configuration{MaxHeight: 1.225}, 1.24, 1.225},
+		{"below the soft limit", config.Configuration{MinHeight: 0.70}, 0.66, 0.70},
+		{"soft limit beyond the desk", config.Configuration{MaxHeight: 1.50}, 1.40, desk.MaxHeight},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if height := clampHeight(&test.configuration, "stand", test.height); height != test.expected {
+				t.Errorf("expected %.4f, got %.4f", test.expected, height)
+			}
+		})
+	}
+}
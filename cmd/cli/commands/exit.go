@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"errors"
+	"idasen-desk/internal/blue"
+	"idasen-desk/internal/desk"
+	"idasen-desk/internal/desk/dpg"
+	"idasen-desk/internal/duty"
+	"idasen-desk/internal/lock"
+)
+
+// Exit codes of the CLI, allowing scripts to react to why a command failed.
+// These are documented in the README and must not change.
+const (
+	ExitOK                    = 0
+	ExitError                 = 1
+	ExitNotConfigured         = 3
+	ExitConnectFailed         = 4
+	ExitCharacteristicMissing = 5
+	ExitOutOfRange            = 6
+	ExitSafetyStop            = 7
+	ExitManualOverride        = 8
+	ExitTimeout               = 9
+	ExitBusy                  = 10
+	ExitLocked                = 11
+	ExitDutyCycle             = 12
+)
+
+// ExitCode returns the exit code the CLI should exit with for the given error
+// returned by a command.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, desk.ErrNotConfigured):
+		return ExitNotConfigured
+	case errors.Is(err, desk.ErrConnectFailed):
+		return ExitConnectFailed
+	case errors.Is(err, desk.ErrCharacteristicMissing):
+		return ExitCharacteristicMissing
+	case errors.Is(err, desk.ErrOutOfRange):
+		return ExitOutOfRange
+	case errors.Is(err, desk.ErrSafetyStop):
+		return ExitSafetyStop
+	case errors.Is(err, desk.ErrManualOverride):
+		return ExitManualOverride
+	case errors.Is(err, desk.ErrTimeout),
+		errors.Is(err, dpg.ErrTimeout),
+		errors.Is(err, blue.ErrScanTimeout):
+		return ExitTimeout
+	case errors.Is(err, lock.ErrBusy):
+		return ExitBusy
+	case errors.Is(err, desk.ErrLocked):
+		return ExitLocked
+	case errors.Is(err, duty.ErrExceeded):
+		return ExitDutyCycle
+	}
+
+	return ExitError
+}
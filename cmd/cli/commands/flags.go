@@ -1,9 +1,26 @@
 package commands
 
+import "time"
+
 type InputFlags struct {
-	ConfigPath  string  `json:"config_path"`
-	Verbose     bool    `json:"verbose"`
-	SitHeight   float64 `json:"sit_height"`
-	StandHeight float64 `json:"stand_height"`
-	Position    float64 `json:"position"`
+	ConfigPath  string        `json:"config_path"`
+	Profile     string        `json:"profile"`
+	Verbose     bool          `json:"verbose"`
+	SitHeight   float64       `json:"sit_height"`
+	StandHeight float64       `json:"stand_height"`
+	Position    float64       `json:"position"`
+	Pull        bool          `json:"pull"`
+	JSON        bool          `json:"json"`
+	Timeout     time.Duration `json:"timeout"`
+	LockMode    string        `json:"lock_mode"`
+	LockTimeout time.Duration `json:"lock_timeout"`
+	UntilIdle   bool          `json:"until_idle"`
+	UntilAbove  float64       `json:"until_above"`
+	UntilBelow  float64       `json:"until_below"`
+	For         time.Duration `json:"for"`
+	By          string        `json:"by"`
+	Hold        bool          `json:"hold"`
+	BodyHeight  string        `json:"body_height"`
+	ElbowHeight string        `json:"elbow_height"`
+	Save        bool          `json:"save"`
 }
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"idasen-desk/internal/desk"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+type deskReport struct {
+	Address string `json:"address"`
+	desk.DeskInfo
+
+	Capabilities    string  `json:"capabilities,omitempty"`
+	MemoryPositions int     `json:"memory_positions"`
+	BaseOffset      float64 `json:"base_offset,omitempty"`
+	ProductInfo     string  `json:"product_info,omitempty"`
+	MinHeight       float64 `json:"min_height"`
+	MaxHeight       float64 `json:"max_height"`
+}
+
+func Info(_ *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	report := deskReport{
+		Address:  configuration.ConnectionAddress,
+		DeskInfo: d.Info(),
+	}
+
+	report.MinHeight, report.MaxHeight = d.Limits()
+
+	// The DPG values are informational and not reported by every controller,
+	// so failures are logged rather than aborting the command.
+	if client, dpgErr := d.DPG(); dpgErr != nil {
+		log.WithError(dpgErr).Warn("desk does not support dpg commands")
+	} else {
+		if capabilities, capabilitiesErr := client.Capabilities(); capabilitiesErr != nil {
+			log.WithError(capabilitiesErr).Warn("failed to read desk capabilities")
+		} else {
+			report.Capabilities = capabilities.String()
+			report.MemoryPositions = capabilities.MemorySize
+		}
+
+		if offset, ok, offsetErr := client.DeskOffset(); offsetErr != nil {
+			log.WithError(offsetErr).Warn("failed to read desk offset")
+		} else if ok {
+			report.BaseOffset = offset.Meters()
+		}
+
+		if product, productErr := client.ProductInfo(); productErr != nil {
+			log.WithError(productErr).Warn("failed to read product info")
+		} else {
+			report.ProductInfo = product.String()
+		}
+	}
+
+	if args.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	log.Printf("name: %s", valueOrUnknown(report.DeviceName))
+	log.Printf("address: %s", report.Address)
+	log.Printf("manufacturer: %s", valueOrUnknown(report.Manufacturer))
+	log.Printf("model: %s", valueOrUnknown(report.ModelNumber))
+	log.Printf("serial: %s", valueOrUnknown(report.SerialNumber))
+	log.Printf("firmware revision: %s", valueOrUnknown(report.FirmwareRevision))
+	log.Printf("hardware revision: %s", valueOrUnknown(report.HardwareRevision))
+	log.Printf("software revision: %s", valueOrUnknown(report.SoftwareRevision))
+	log.Printf("capabilities: %s", valueOrUnknown(report.Capabilities))
+	log.Printf("memory positions: %d", report.MemoryPositions)
+	log.Printf("base offset: %s", valueOrUnknown(formatMeters(report.BaseOffset)))
+	log.Printf("product info: %s", valueOrUnknown(report.ProductInfo))
+	log.Printf("limits: %.2f - %.2f", report.MinHeight, report.MaxHeight)
+
+	return nil
+}
+
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+
+	return value
+}
+
+func formatMeters(value float64) string {
+	if value == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%.4f", value)
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"fmt"
+	"idasen-desk/internal/config"
+	"idasen-desk/internal/desk"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// jogDuration is how long the desk is jogged for without a duration, distance
+// or hold, about the length of a single press of the physical buttons.
+const jogDuration = time.Second
+
+func Up(_ *cli.Context, args InputFlags) error {
+	return jog(args, desk.UP)
+}
+
+func Down(_ *cli.Context, args InputFlags) error {
+	return jog(args, desk.DOWN)
+}
+
+func jog(args InputFlags, direction desk.Direction) (err error) {
+	if args.By != "" && (args.For > 0 || args.Hold) || args.For > 0 && args.Hold {
+		return fmt.Errorf("only one of --for, --by or --hold can be provided")
+	}
+
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	if args.By != "" {
+		return jogBy(configuration, args, direction)
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	log.Printf("connected to %s", d.Name())
+
+	duration := args.For
+	if duration == 0 && !args.Hold {
+		duration = jogDuration
+	}
+
+	done := make(chan struct{})
+
+	if args.Hold {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(c)
+
+		go func() {
+			<-c
+			close(done)
+		}()
+
+		log.Info("holding, press ctrl+c to stop")
+	}
+
+	return d.Jog(direction, duration, done)
+}
+
+// jogBy moves the desk by a distance, which can be routed through another
+// process holding the desk.
+func jogBy(configuration *config.Configuration, args InputFlags, direction desk.Direction) (err error) {
+	distance, err := parseDistance(args.By)
+	if err != nil {
+		return err
+	}
+
+	if direction == desk.DOWN {
+		distance = -distance
+	}
+
+	var d controller
+	if d, err = openController(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	height, err := d.GetHeight()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("connected to %s", d.Name())
+	return d.MoveToTarget(height + distance)
+}
+
+// parseDistance parses a positive distance into meters, e.g. 3cm, 15mm, 0.1m
+// or 0.1 which defaults to meters.
+func parseDistance(value string) (float64, error) {
+	number, scale := strings.TrimSpace(value), 1.0
+
+	for _, unit := range []struct {
+		suffix string
+		scale  float64
+	}{{"mm", 0.001}, {"cm", 0.01}, {"m", 1}} {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, scale = strings.TrimSuffix(number, unit.suffix), unit.scale
+			break
+		}
+	}
+
+	distance, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || distance <= 0 {
+		return 0, fmt.Errorf("distance must be a positive number with an optional unit of mm, cm or m, got %q", value)
+	}
+
+	// Rounded to the resolution of the desk, avoiding float noise such as
+	// 1.9000000000000001 when saving a distance to the configuration.
+	return math.Round(distance*scale*10000) / 10000, nil
+}
@@ -0,0 +1,38 @@
+package commands
+
+import "testing"
+
+func TestParseDistance(t *testing.T) {
+	tests := []struct {
+		value    string
+		distance float64
+	}{
+		{"3cm", 0.03},
+		{"15mm", 0.015},
+		{"0.1m", 0.1},
+		{"0.1", 0.1},
+		{" 2 cm ", 0.02},
+		{"1.9cm", 0.019},
+		{"0.05mm", 0.0001},
+	}
+
+	for _, test := range tests {
+		distance, err := parseDistance(test.value)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", test.value, err)
+			continue
+		}
+
+		if distance != test.distance {
+			t.Errorf("%q: expected %v, got %v", test.value, test.distance, distance)
+		}
+	}
+}
+
+func TestParseDistanceInvalid(t *testing.T) {
+	for _, value := range []string{"", "cm", "-3cm", "0", "3in", "three"} {
+		if _, err := parseDistance(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+func MemoryList(_ *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	slots, err := d.MemorySlots()
+	if err != nil {
+		return err
+	}
+
+	for slot := 1; slot <= slots; slot++ {
+		height, ok, positionErr := d.MemoryPosition(slot)
+
+		switch {
+		case positionErr != nil:
+			log.WithError(positionErr).Warnf("memory %d: failed to read", slot)
+		case !ok:
+			log.Printf("memory %d: not set", slot)
+		default:
+			log.Printf("memory %d: %.2f", slot, height)
+		}
+	}
+
+	return nil
+}
+
+func MemorySet(ctx *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	var slot int
+	if slot, err = strconv.Atoi(ctx.Args().Get(0)); err != nil {
+		return fmt.Errorf("memory slot must be a valid number")
+	}
+
+	var height float64
+	if height, err = strconv.ParseFloat(ctx.Args().Get(1), 64); err != nil {
+		return fmt.Errorf("height must be a valid number")
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	if err = d.SetMemoryPosition(slot, height); err != nil {
+		return fmt.Errorf("failed to set memory position %d, %w", slot, err)
+	}
+
+	log.Printf("memory %d: %.2f", slot, height)
+	return nil
+}
+
+func MemoryGo(ctx *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	var slot int
+	if slot, err = strconv.Atoi(ctx.Args().First()); err != nil {
+		return fmt.Errorf("memory slot must be a valid number")
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	log.Printf("connected to %s", d.Name())
+	return d.MoveToMemoryPosition(slot)
+}
+
+// MemorySync keeps the configured sit and stand presets and the desks own
+// memory positions consistent. By default the presets are written to the desk,
+// with --pull the desk memory positions are written to the configuration.
+func MemorySync(_ *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	if configuration.MemorySlots.Sit == 0 && configuration.MemorySlots.Stand == 0 {
+		return fmt.Errorf("no memory slots to sync, set memory_slots.sit or memory_slots.stand")
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	presets := []struct {
+		name   string
+		slot   int
+		height *float64
+	}{
+		{"sit", configuration.MemorySlots.Sit, &configuration.SitHeight},
+		{"stand", configuration.MemorySlots.Stand, &configuration.StandHeight},
+	}
+
+	for _, preset := range presets {
+		if preset.slot == 0 {
+			continue
+		}
+
+		if !args.Pull {
+			if err = d.SetMemoryPosition(preset.slot, *preset.height); err != nil {
+				return fmt.Errorf("failed to set memory position %d, %w", preset.slot, err)
+			}
+
+			log.Printf("%s: %.2f -> memory %d", preset.name, *preset.height, preset.slot)
+			continue
+		}
+
+		height, ok, positionErr := d.MemoryPosition(preset.slot)
+		if positionErr != nil {
+			return fmt.Errorf("failed to read memory position %d, %w", preset.slot, positionErr)
+		}
+
+		if !ok {
+			log.Printf("%s: memory %d is not set, keeping %.2f", preset.name, preset.slot, *preset.height)
+			continue
+		}
+
+		*preset.height = height
+		log.Printf("%s: memory %d -> %.2f", preset.name, preset.slot, height)
+	}
+
+	if args.Pull {
+		return configuration.Save(args.ConfigPath)
+	}
+
+	return nil
+}
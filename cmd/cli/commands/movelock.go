@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"idasen-desk/internal/config"
+	"idasen-desk/internal/guard"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// Lock locks the desk against movement until it is unlocked, including
+// requests routed through a process holding the desk.
+func Lock(_ *cli.Context, args InputFlags) error {
+	return setLocked(args, true)
+}
+
+// Unlock allows the desk to be moved again after Lock. The desk stays locked
+// during quiet hours.
+func Unlock(_ *cli.Context, args InputFlags) error {
+	return setLocked(args, false)
+}
+
+func setLocked(args InputFlags, locked bool) error {
+	configuration, err := config.Load(args.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	configuration.Locked = locked
+
+	if err = configuration.Save(args.ConfigPath); err != nil {
+		return err
+	}
+
+	if locked {
+		log.Printf("desk locked")
+		return nil
+	}
+
+	log.Printf("desk unlocked")
+
+	if err = guard.New(args.ConfigPath).Check(); err != nil {
+		log.Warn(err)
+	}
+
+	return nil
+}
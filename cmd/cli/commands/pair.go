@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+	"idasen-desk/internal/blue"
+	"idasen-desk/internal/desk"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"tinygo.org/x/bluetooth"
+)
+
+// Pair guides the first time setup of a desk: waiting for the desk to enter
+// pairing mode, bonding with it, registering this device as a user of the desk
+// and recording the result within the configuration.
+func Pair(_ *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("hold the bluetooth button on the desk controller until the light starts flashing")
+	log.Printf("waiting up to %s for a desk in pairing mode", args.Timeout)
+
+	// Without a configured address, the first device advertising the desk
+	// service is paired. Otherwise only the configured desk is accepted.
+	result, err := blue.WaitForDevice(args.Timeout, func(result *bluetooth.ScanResult) bool {
+		if configuration.ConnectionAddress != "" {
+			return strings.EqualFold(result.Address.String(), configuration.ConnectionAddress)
+		}
+
+		return result.HasServiceUUID(desk.UuidAdvSvc)
+	})
+
+	if err != nil {
+		return fmt.Errorf("no desk found in pairing mode, %w", err)
+	}
+
+	log.Printf("found desk %s (%s), pairing", result.Address.String(), result.LocalName())
+
+	if err = blue.Pair(result.Address); err != nil {
+		return err
+	}
+
+	configuration.ConnectionAddress = result.Address.String()
+	if result.LocalName() != "" {
+		configuration.LocalName = result.LocalName()
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	var userID []byte
+	if userID, err = hex.DecodeString(configuration.UserID); err != nil || len(userID) == 0 {
+		if userID, err = desk.NewUserID(); err != nil {
+			return err
+		}
+	}
+
+	if err = d.RegisterUser(userID); err != nil {
+		return err
+	}
+
+	configuration.UserID = hex.EncodeToString(userID)
+	configuration.Paired = true
+
+	if err = configuration.Save(args.ConfigPath); err != nil {
+		return err
+	}
+
+	log.Printf("paired with %s", d.Name())
+	return nil
+}
@@ -2,8 +2,6 @@ package commands
 
 import (
 	"fmt"
-	"idasen-desk/internal/config"
-	"idasen-desk/internal/desk"
 	"strconv"
 
 	log "github.com/sirupsen/logrus"
@@ -11,7 +9,7 @@ import (
 )
 
 func Position(ctx *cli.Context, args InputFlags) (err error) {
-	configuration, err := config.Load(args.ConfigPath)
+	configuration, err := loadConfig(args)
 	if err != nil {
 		return err
 	}
@@ -21,15 +19,13 @@ func Position(ctx *cli.Context, args InputFlags) (err error) {
 		return fmt.Errorf("input argument must be a valid number")
 	}
 
-	var d *desk.Desk
-	if d, err = desk.NewDesk(
-		configuration.LocalName,
-		configuration.ConnectionAddress,
-		true,
-	); err != nil {
-		return fmt.Errorf("failed to create new desk instance, %w", err)
+	var d controller
+	if d, err = openController(configuration, args); err != nil {
+		return err
 	}
 
+	defer closeDesk(d)
+
 	log.Printf("connected to %s", d.Name())
 	return d.MoveToTarget(targetPosition)
 }
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"fmt"
+	"idasen-desk/internal/config"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// ProfileList lists the profiles within the configuration, marking the active
+// profile.
+func ProfileList(_ *cli.Context, args InputFlags) (err error) {
+	configuration, err := config.Load(args.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(configuration.Profiles))
+	for name := range configuration.Profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		log.Printf("no profiles, add one with: desk profile add NAME")
+	}
+
+	for _, name := range names {
+		profile := configuration.Profiles[name]
+
+		marker := " "
+		if name == configuration.Profile {
+			marker = "*"
+		}
+
+		log.Printf("%s %s: sit %s, stand %s", marker, name,
+			heightOrDefault(profile.SitHeight), heightOrDefault(profile.StandHeight))
+	}
+
+	return nil
+}
+
+// ProfileAdd adds a profile to the configuration, starting with the current
+// presets, limits and body measurements.
+func ProfileAdd(ctx *cli.Context, args InputFlags) (err error) {
+	configuration, err := config.Load(args.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	name := ctx.Args().First()
+	if name == "" {
+		return fmt.Errorf("profile name must be provided")
+	}
+
+	if _, exists := configuration.Profiles[name]; exists {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+
+	if configuration.Profiles == nil {
+		configuration.Profiles = map[string]config.Profile{}
+	}
+
+	configuration.Profiles[name] = config.Profile{}
+
+	if err = configuration.Save(args.ConfigPath); err != nil {
+		return err
+	}
+
+	log.Printf("added profile %s, switch to it with: desk profile use %s", name, name)
+	return nil
+}
+
+// ProfileUse switches the active profile, used by all commands unless another
+// profile is selected with the profile flag. An empty name deactivates
+// profiles.
+func ProfileUse(ctx *cli.Context, args InputFlags) (err error) {
+	configuration, err := config.Load(args.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	name := ctx.Args().First()
+
+	if _, exists := configuration.Profiles[name]; name != "" && !exists {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+
+	configuration.Profile = name
+
+	if err = configuration.Save(args.ConfigPath); err != nil {
+		return err
+	}
+
+	if name == "" {
+		log.Printf("no profile in use")
+	} else {
+		log.Printf("using profile %s", name)
+	}
+
+	return nil
+}
+
+func heightOrDefault(height float64) string {
+	if height == 0 {
+		return "default"
+	}
+
+	return fmt.Sprintf("%.2f", height)
+}
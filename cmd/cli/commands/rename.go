@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+func Rename(ctx *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(ctx.Args().First())
+	if name == "" {
+		return fmt.Errorf("a new name for the desk must be provided")
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	log.Printf("renaming %s to %s", d.Name(), name)
+
+	if err = d.Rename(name); err != nil {
+		return fmt.Errorf("failed to rename desk, %w", err)
+	}
+
+	configuration.LocalName = name
+	if err = configuration.Save(args.ConfigPath); err != nil {
+		return err
+	}
+
+	log.Printf("renamed desk to %s", name)
+	return nil
+}
@@ -0,0 +1,263 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"idasen-desk/internal/config"
+	"idasen-desk/internal/desk"
+	"idasen-desk/internal/duty"
+	"idasen-desk/internal/guard"
+	"idasen-desk/internal/history"
+	"idasen-desk/internal/rules"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// rulesInterval is how often the rules are evaluated while running.
+const rulesInterval = 30 * time.Second
+
+// RulesRun evaluates the rules until interrupted, moving the desk whenever a
+// rule fires.
+func RulesRun(_ *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	log.Printf("connected to %s", d.Name())
+
+	engine, err := newRulesEngine(configuration, args, d)
+	if err != nil {
+		return err
+	}
+
+	unsubscribe, err := d.SubscribeHeight(func(height, _ float64) {
+		engine.Observe(height, time.Now())
+	})
+
+	if err != nil {
+		return err
+	}
+
+	defer unsubscribe()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+
+	ticker := time.NewTicker(rulesInterval)
+	defer ticker.Stop()
+
+	log.Infof("evaluating %d rules every %s, press ctrl+c to stop", len(configuration.Rules), rulesInterval)
+
+	for {
+		// The height is followed from the notifications and only read before
+		// a rule fires, so the position is correct even if a notification was
+		// missed or the connection was released meanwhile. Reading it on every
+		// evaluation would keep the connection from ever being released.
+		if fires(engine.Evaluate(time.Now())) {
+			if height, err := d.GetHeight(); err != nil {
+				log.WithError(err).Warn("failed to read the desk height")
+			} else {
+				engine.Observe(height, time.Now())
+			}
+		}
+
+		decisions, err := engine.Run(time.Now())
+
+		for _, decision := range decisions {
+			if decision.Fire {
+				log.Infof("rule %s fired: %s", decision.Rule, decision.Reason)
+			} else {
+				log.Debugf("rule %s did not fire: %s", decision.Rule, decision.Reason)
+			}
+		}
+
+		if err != nil {
+			log.WithError(err).Warn("rule did not complete")
+		}
+
+		select {
+		case <-c:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fires returns true if a rule fires with the decisions.
+func fires(decisions []rules.Decision) bool {
+	for _, decision := range decisions {
+		if decision.Fire {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RulesExplain explains which rule would fire right now and why every other
+// rule would not, without moving the desk.
+func RulesExplain(_ *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	engine, err := newRulesEngine(configuration, args, d)
+	if err != nil {
+		return err
+	}
+
+	decisions := engine.Evaluate(time.Now())
+
+	if args.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(decisions)
+	}
+
+	position, since := engine.Position()
+	log.Printf("desk is %s since %s", position, since.Format("Mon 15:04:05"))
+
+	if len(decisions) == 0 {
+		log.Printf("no rules, add them to the rules within the configuration")
+	}
+
+	for _, decision := range decisions {
+		marker := " "
+		if decision.Fire {
+			marker = "*"
+		}
+
+		log.Printf("%s %s: %s", marker, decision.Rule, decision.Reason)
+	}
+
+	return nil
+}
+
+// newRulesEngine creates the engine for the configured rules, seeded with the
+// current height of the desk and the history as far back as the rules need.
+func newRulesEngine(configuration *config.Configuration, args InputFlags, d *session) (*rules.Engine, error) {
+	configured, err := configRules(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	// Heights closer to the stand height than the sit height are standing.
+	threshold := (configuration.SitHeight + configuration.StandHeight) / 2
+	engine := rules.New(configured, threshold, d, rulesGuard(configuration, args))
+
+	height, err := d.GetHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var events []desk.Event
+	if configuration.HistoryPath != "" {
+		since := now.Add(-engine.Lookback())
+
+		if events, err = history.New(configuration.HistoryPath).Since(since); err != nil {
+			return nil, err
+		}
+	}
+
+	engine.Seed(height, events, now)
+	return engine, nil
+}
+
+// rulesGuard checks every guard the desk checks before moving, so a rule the
+// desk would refuse doesn't fire. The duty cycle never delays the check, the
+// rule fires once the motor can run again instead.
+func rulesGuard(configuration *config.Configuration, args InputFlags) func(distance float64) error {
+	lockGuard := guard.New(args.ConfigPath)
+
+	var cycle *duty.Cycle
+	if configuration.HistoryPath != "" {
+		cycle = dutyCycle(configuration, history.New(configuration.HistoryPath))
+	}
+
+	return func(distance float64) error {
+		if err := lockGuard.Check(); err != nil {
+			return err
+		}
+
+		if cycle != nil {
+			return cycle.Allow(distance)
+		}
+
+		return nil
+	}
+}
+
+// configRules converts the configured rules to the rules of the engine.
+func configRules(configuration *config.Configuration) ([]rules.Rule, error) {
+	converted := make([]rules.Rule, 0, len(configuration.Rules))
+
+	for i, rule := range configuration.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		position := rules.Position(rule.When.Position)
+		if position != rules.Sitting && position != rules.Standing {
+			return nil, fmt.Errorf("rule %s must be either sitting or standing, got %q", name, rule.When.Position)
+		}
+
+		var target float64
+
+		switch rule.Then {
+		case "sit":
+			target = configuration.SitHeight
+		case "stand":
+			target = configuration.StandHeight
+		default:
+			return nil, fmt.Errorf("rule %s must either sit or stand, got %q", name, rule.Then)
+		}
+
+		if (rule.When.Start == "") != (rule.When.End == "") {
+			return nil, fmt.Errorf("rule %s must have both a start and an end", name)
+		}
+
+		days, err := rules.ParseDays(rule.When.Days)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse days of rule %s, %w", name, err)
+		}
+
+		converted = append(converted, rules.Rule{
+			Name:     name,
+			Priority: rule.Priority,
+			Position: position,
+			For:      rule.When.For,
+			Start:    rule.When.Start,
+			End:      rule.When.End,
+			Days:     days,
+			Target:   target,
+			Cooldown: rule.Cooldown,
+		})
+	}
+
+	return converted, nil
+}
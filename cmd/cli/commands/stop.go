@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"idasen-desk/internal/lock"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+func Stop(_ *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	// Waiting for the desk to be free would wait for the very move that should
+	// be stopped, so the stop is always routed to the process moving the desk.
+	args.LockMode = string(lock.ModeRoute)
+
+	var d controller
+	if d, err = openController(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	log.Printf("stopping %s", d.Name())
+	return d.StopMove()
+}
@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"idasen-desk/internal/desk"
+	"io"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// gaugeWidth is the number of characters used to draw the height gauge.
+const gaugeWidth = 50
+
+// tuiFormatter formats log entries as short lines for the movement log of the
+// dashboard.
+type tuiFormatter struct{}
+
+func (tuiFormatter) Format(entry *log.Entry) ([]byte, error) {
+	message := tview.Escape(entry.Message)
+
+	if entry.Level <= log.WarnLevel {
+		message = fmt.Sprintf("[red]%s[-]", message)
+	}
+
+	return []byte(fmt.Sprintf("%s %s\n", entry.Time.Format("15:04:05"), message)), nil
+}
+
+// redirectLog writes log output to the writer, e.g. a view of a full-screen
+// command, until the returned function is called.
+func redirectLog(w io.Writer) func() {
+	output, formatter := log.StandardLogger().Out, log.StandardLogger().Formatter
+	log.SetOutput(w)
+	log.SetFormatter(tuiFormatter{})
+
+	return func() {
+		log.SetOutput(output)
+		log.SetFormatter(formatter)
+	}
+}
+
+// isCancelledMove returns true if the move failed because it was replaced or
+// stopped by another move, which is expected when controlling the desk
+// interactively.
+func isCancelledMove(err error) bool {
+	return errors.Is(err, desk.ErrMovePreempted) || errors.Is(err, desk.ErrMoveStopped)
+}
+
+// heightGauge draws the height of the desk as a bar between its limits.
+func heightGauge(height, speed, minHeight, maxHeight float64) string {
+	filled := int((height - minHeight) / (maxHeight - minHeight) * gaugeWidth)
+	filled = max(0, min(gaugeWidth, filled))
+
+	direction := ""
+	if speed > 0 {
+		direction = " ▲"
+	} else if speed < 0 {
+		direction = " ▼"
+	}
+
+	return fmt.Sprintf("%.2f [%s%s] %.2f\n\nheight: %.3f m\nspeed:  %.1f mm/s%s",
+		minHeight, strings.Repeat("█", filled), strings.Repeat("░", gaugeWidth-filled), maxHeight,
+		height, speed*1000, direction)
+}
+
+// Tui shows a live dashboard of the desk, allowing it to be controlled from
+// the keyboard.
+func Tui(_ *cli.Context, args InputFlags) (err error) {
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	app := tview.NewApplication()
+
+	header := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetText(d.Name())
+
+	gauge := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter)
+
+	movements := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetChangedFunc(func() {
+			app.Draw()
+		})
+
+	movements.SetTitle(" movements ").SetBorder(true)
+
+	help := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetText("↑/↓ jog  1-4 memory slot  s stand  d sit  space stop  q quit")
+
+	grid := tview.NewGrid().
+		SetRows(1, 5, 0, 1).
+		SetBorders(true).
+		AddItem(header, 0, 0, 1, 1, 0, 0, false).
+		AddItem(gauge, 1, 0, 1, 1, 0, 0, false).
+		AddItem(movements, 2, 0, 1, 1, 0, 0, false).
+		AddItem(help, 3, 0, 1, 1, 0, 0, false)
+
+	grid.SetBackgroundColor(tcell.ColorDefault)
+	header.SetBackgroundColor(tcell.ColorDefault)
+	gauge.SetBackgroundColor(tcell.ColorDefault)
+	movements.SetBackgroundColor(tcell.ColorDefault)
+	help.SetBackgroundColor(tcell.ColorDefault)
+
+	// Log output would draw over the dashboard, so it is shown in the
+	// movement log instead while the dashboard is open.
+	defer redirectLog(movements)()
+
+	minHeight, maxHeight := d.Limits()
+
+	unsubscribe, err := d.SubscribeHeight(func(height, speed float64) {
+		app.QueueUpdateDraw(func() {
+			gauge.SetText(heightGauge(height, speed, minHeight, maxHeight))
+		})
+	})
+
+	if err != nil {
+		return err
+	}
+
+	defer unsubscribe()
+
+	height, err := d.GetHeight()
+	if err != nil {
+		return err
+	}
+
+	gauge.SetText(heightGauge(height, 0, minHeight, maxHeight))
+
+	// Moves run in the background so the dashboard stays responsive, a new
+	// move preempts the one in flight.
+	run := func(move func() error) {
+		go func() {
+			if err := move(); err != nil && !isCancelledMove(err) {
+				log.WithError(err).Error("failed to move desk")
+			}
+		}()
+	}
+
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			app.Stop()
+			return nil
+		case tcell.KeyUp:
+			run(func() error { return d.Jog(desk.UP, jogDuration, nil) })
+			return nil
+		case tcell.KeyDown:
+			run(func() error { return d.Jog(desk.DOWN, jogDuration, nil) })
+			return nil
+		case tcell.KeyRune:
+		default:
+			return event
+		}
+
+		switch key := event.Rune(); key {
+		case 'q':
+			app.Stop()
+		case 's':
+			run(func() error { return d.MoveToTarget(configuration.StandHeight) })
+		case 'd':
+			run(func() error { return d.MoveToTarget(configuration.SitHeight) })
+		case ' ':
+			run(d.StopMove)
+		case '1', '2', '3', '4':
+			// The memory slots of the desk, not the configured presets,
+			// which are on s and d.
+			run(func() error { return d.MoveToMemoryPosition(int(key - '0')) })
+		default:
+			return event
+		}
+
+		return nil
+	})
+
+	log.Infof("connected to %s", d.Name())
+
+	return app.SetRoot(grid, true).
+		EnableMouse(false).
+		Run()
+}
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// Wait blocks until the desk is idle, or has reached a height, exiting with
+// ExitTimeout if the condition is not met within the timeout.
+func Wait(_ *cli.Context, args InputFlags) (err error) {
+	conditions := 0
+	for _, set := range []bool{args.UntilIdle, args.UntilAbove != 0, args.UntilBelow != 0} {
+		if set {
+			conditions++
+		}
+	}
+
+	if conditions != 1 {
+		return fmt.Errorf("exactly one of --until-idle, --until-above or --until-below must be provided")
+	}
+
+	configuration, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	var d *session
+	if d, err = connectDesk(configuration, args); err != nil {
+		return err
+	}
+
+	defer closeDesk(d)
+
+	var height float64
+
+	switch {
+	case args.UntilIdle:
+		height, err = d.WaitUntilIdle(args.Timeout)
+	case args.UntilAbove != 0:
+		height, err = d.WaitUntilAbove(args.UntilAbove, args.Timeout)
+	default:
+		height, err = d.WaitUntilBelow(args.UntilBelow, args.Timeout)
+	}
+
+	if err != nil {
+		return fmt.Errorf("desk at %.4f, %w", height, err)
+	}
+
+	log.Infof("desk at %.4f", height)
+	return nil
+}
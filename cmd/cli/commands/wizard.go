@@ -0,0 +1,205 @@
+package commands
+
+import (
+	"fmt"
+	"idasen-desk/internal/config"
+	"idasen-desk/internal/desk"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+)
+
+// heightStep is a height captured by the height wizard.
+type heightStep struct {
+	name   string
+	height *float64
+}
+
+// heightWizard continues the configure command once a desk has been selected,
+// capturing the sitting and standing heights by jogging the desk to them. All
+// values are saved together once the last height has been captured.
+type heightWizard struct {
+	app           *tview.Application
+	configuration *config.Configuration
+	args          InputFlags
+
+	desk       *session
+	restoreLog func()
+	started    chan struct{}
+
+	// connected, height and step are only accessed from the event loop.
+	connected bool
+	view      *tview.TextView
+	steps     []heightStep
+	step      int
+	height    float64
+
+	// err is the reason the wizard failed, set once by finish.
+	err      error
+	finished sync.Once
+}
+
+func newHeightWizard(app *tview.Application, configuration *config.Configuration, args InputFlags) *heightWizard {
+	return &heightWizard{
+		app:           app,
+		configuration: configuration,
+		args:          args,
+		started:       make(chan struct{}),
+		steps: []heightStep{
+			{name: "sitting", height: &configuration.SitHeight},
+			{name: "standing", height: &configuration.StandHeight},
+		},
+	}
+}
+
+// Start connects to the selected desk and shows the first step. Blocks while
+// connecting, so must not be called from the event loop.
+func (w *heightWizard) Start() {
+	defer close(w.started)
+
+	w.view = tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetText("connecting to desk...")
+
+	logs := tview.NewTextView().
+		SetDynamicColors(true).
+		SetChangedFunc(func() {
+			w.app.Draw()
+		})
+
+	grid := tview.NewGrid().
+		SetRows(3, 7, 0).
+		SetBorders(true).
+		AddItem(HeaderPrimitive(w.configuration), 0, 0, 1, 1, 0, 0, false).
+		AddItem(w.view, 1, 0, 1, 1, 0, 0, false).
+		AddItem(logs, 2, 0, 1, 1, 0, 0, false)
+
+	grid.SetBackgroundColor(tcell.ColorDefault)
+	w.view.SetBackgroundColor(tcell.ColorDefault)
+	logs.SetBackgroundColor(tcell.ColorDefault)
+
+	w.app.QueueUpdateDraw(func() {
+		w.app.SetRoot(grid, true)
+		w.app.SetInputCapture(w.handleKey)
+	})
+
+	// Log output would draw over the wizard, it is restored once the wizard
+	// has been closed.
+	w.restoreLog = redirectLog(logs)
+
+	var err error
+	if w.desk, err = connectDesk(w.configuration, w.args); err != nil {
+		w.finish(fmt.Errorf("failed to connect to desk to capture heights, %w", err))
+		return
+	}
+
+	height, err := w.desk.GetHeight()
+	if err != nil {
+		w.finish(fmt.Errorf("failed to get desk height, %w", err))
+		return
+	}
+
+	_, err = w.desk.SubscribeHeight(func(height, _ float64) {
+		w.app.QueueUpdateDraw(func() {
+			w.height = height
+			w.render()
+		})
+	})
+
+	if err != nil {
+		w.finish(err)
+		return
+	}
+
+	w.app.QueueUpdateDraw(func() {
+		w.connected = true
+		w.height = height
+		w.render()
+	})
+}
+
+// Close waits for the wizard to finish connecting and disconnects from the
+// desk, if the wizard connected to it.
+func (w *heightWizard) Close() {
+	<-w.started
+
+	if w.desk != nil {
+		closeDesk(w.desk)
+	}
+
+	if w.restoreLog != nil {
+		w.restoreLog()
+	}
+}
+
+func (w *heightWizard) render() {
+	if w.step >= len(w.steps) {
+		return
+	}
+
+	step := w.steps[w.step]
+
+	w.view.SetText(fmt.Sprintf(
+		"step %d of %d: move the desk to a comfortable %s height\n\n"+
+			"current: %.3f m (configured: %.3f m)\n\n"+
+			"↑/↓ jog  enter capture  esc skip and save the desk only",
+		w.step+1, len(w.steps), step.name, w.height, *step.height,
+	))
+}
+
+func (w *heightWizard) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	// Keys are ignored while connecting to the desk.
+	if !w.connected && event.Key() != tcell.KeyEsc {
+		return nil
+	}
+
+	switch event.Key() {
+	case tcell.KeyUp:
+		w.jog(desk.UP)
+	case tcell.KeyDown:
+		w.jog(desk.DOWN)
+	case tcell.KeyEnter:
+		*w.steps[w.step].height = w.height
+		log.Infof("captured %s height of %.3f", w.steps[w.step].name, w.height)
+
+		if w.step++; w.step >= len(w.steps) {
+			w.finish(nil)
+			return nil
+		}
+
+		w.render()
+	case tcell.KeyEsc:
+		w.finish(nil)
+	}
+
+	return nil
+}
+
+// jog moves the desk in the background so the wizard stays responsive.
+func (w *heightWizard) jog(direction desk.Direction) {
+	go func() {
+		if err := w.desk.Jog(direction, jogDuration, nil); err != nil && !isCancelledMove(err) {
+			log.WithError(err).Error("failed to move desk")
+		}
+	}()
+}
+
+// finish saves the configuration, including the desk selection and all
+// captured heights, and stops the wizard.
+func (w *heightWizard) finish(err error) {
+	w.finished.Do(func() {
+		if saveErr := w.configuration.Save(w.args.ConfigPath); saveErr != nil && err == nil {
+			err = saveErr
+		}
+
+		w.err = err
+		w.app.Stop()
+	})
+}
+
+// Err returns the reason the wizard failed, once it has been closed.
+func (w *heightWizard) Err() error {
+	return w.err
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"idasen-desk/cmd/cli/commands"
 	"os"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
@@ -27,6 +28,26 @@ func main() {
 			Value:       "./.desk.yml",
 			Destination: &flags.ConfigPath,
 		},
+		&cli.StringFlag{
+			Name:        "profile",
+			Aliases:     []string{"p"},
+			Usage:       "The user profile to use instead of the active profile",
+			EnvVars:     []string{"DESK_PROFILE"},
+			Destination: &flags.Profile,
+		},
+		&cli.StringFlag{
+			Name:        "lock",
+			Usage:       "When the desk is in use by another process: wait, fail or route through it",
+			EnvVars:     []string{"DESK_LOCK"},
+			Value:       "wait",
+			Destination: &flags.LockMode,
+		},
+		&cli.DurationFlag{
+			Name:        "lock-timeout",
+			Usage:       "How long to wait for the desk when it is in use by another process",
+			Value:       30 * time.Second,
+			Destination: &flags.LockTimeout,
+		},
 	}
 
 	standHeightFlag := &cli.Float64Flag{
@@ -49,7 +70,38 @@ func main() {
 		Destination: &flags.SitHeight,
 	}
 
+	jogFlags := []cli.Flag{
+		&cli.DurationFlag{
+			Name:        "for",
+			Usage:       "How long to move the desk for",
+			DefaultText: "1s",
+			Destination: &flags.For,
+		},
+		&cli.StringFlag{
+			Name:        "by",
+			Usage:       "The distance to move the desk by, e.g. 3cm, 15mm or 0.1m",
+			Destination: &flags.By,
+		},
+		&cli.BoolFlag{
+			Name:        "hold",
+			Usage:       "Keep moving the desk until ctrl+c or its limit",
+			Destination: &flags.Hold,
+		},
+	}
+
 	cliCommands := []*cli.Command{{
+		Name:  "pair",
+		Usage: "Pair with a desk in pairing mode and register as a user of the desk.",
+		Flags: append([]cli.Flag{&cli.DurationFlag{
+			Name:        "timeout",
+			Usage:       "How long to wait for a desk in pairing mode",
+			Value:       time.Minute,
+			Destination: &flags.Timeout,
+		}}, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Pair(context, flags)
+		},
+	}, {
 		Name:  "configure",
 		Usage: "configure the device to connect to.",
 		Flags: append([]cli.Flag{standHeightFlag}, sharedFlags...),
@@ -85,6 +137,89 @@ func main() {
 		Action: func(context *cli.Context) error {
 			return commands.Toggle(context, flags)
 		},
+	}, {
+		Name:  "up",
+		Usage: "Move the desk up for a duration, by a distance or while held.",
+		Flags: append(jogFlags, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Up(context, flags)
+		},
+	}, {
+		Name:  "down",
+		Usage: "Move the desk down for a duration, by a distance or while held.",
+		Flags: append(jogFlags, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Down(context, flags)
+		},
+	}, {
+		Name:  "stop",
+		Usage: "Stop the desk, including a move by another process.",
+		Flags: append([]cli.Flag{}, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Stop(context, flags)
+		},
+	}, {
+		Name:  "tui",
+		Usage: "Show a live dashboard to control the desk from the keyboard.",
+		Flags: append([]cli.Flag{}, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Tui(context, flags)
+		},
+	}, {
+		Name:  "ergonomics",
+		Usage: "Recommend sit and stand heights from your body measurements.",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:        "body-height",
+				Usage:       "Your height barefoot, e.g. 1.80 or 180cm",
+				DefaultText: "Configuration File Value",
+				Destination: &flags.BodyHeight,
+			},
+			&cli.StringFlag{
+				Name:        "elbow-height",
+				Usage:       "The height of your elbow from the floor when standing in shoes, e.g. 112cm",
+				DefaultText: "Configuration File Value",
+				Destination: &flags.ElbowHeight,
+			},
+			&cli.BoolFlag{
+				Name:        "save",
+				Usage:       "Save the recommendation as the sit and stand presets without asking",
+				Destination: &flags.Save,
+			},
+			&cli.BoolFlag{
+				Name:        "json",
+				Usage:       "Print the recommendation as JSON",
+				Destination: &flags.JSON,
+			},
+		}, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Ergonomics(context, flags)
+		},
+	}, {
+		Name:  "lock",
+		Usage: "Lock the desk against movement until unlocked.",
+		Flags: append([]cli.Flag{}, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Lock(context, flags)
+		},
+	}, {
+		Name:  "unlock",
+		Usage: "Allow the desk to be moved again.",
+		Flags: append([]cli.Flag{}, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Unlock(context, flags)
+		},
+	}, {
+		Name:  "duty",
+		Usage: "Report the remaining duty cycle budget of the motor.",
+		Flags: append([]cli.Flag{&cli.BoolFlag{
+			Name:        "json",
+			Usage:       "Print the budget as JSON",
+			Destination: &flags.JSON,
+		}}, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Duty(context, flags)
+		},
 	}, {
 		Name:  "monitor",
 		Usage: "Monitor and log the position of the desk as it moves",
@@ -92,6 +227,140 @@ func main() {
 		Action: func(context *cli.Context) error {
 			return commands.Monitor(context, flags)
 		},
+	}, {
+		Name:  "wait",
+		Usage: "Wait until the desk is idle or has reached a height.",
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:        "until-idle",
+				Usage:       "Wait until the desk has stopped moving",
+				Destination: &flags.UntilIdle,
+			},
+			&cli.Float64Flag{
+				Name:        "until-above",
+				Usage:       "Wait until the desk is at or above the height",
+				Destination: &flags.UntilAbove,
+			},
+			&cli.Float64Flag{
+				Name:        "until-below",
+				Usage:       "Wait until the desk is at or below the height",
+				Destination: &flags.UntilBelow,
+			},
+			&cli.DurationFlag{
+				Name:        "timeout",
+				Usage:       "How long to wait before giving up, zero waits forever",
+				Destination: &flags.Timeout,
+			},
+		}, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Wait(context, flags)
+		},
+	}, {
+		Name:  "memory",
+		Usage: "Read and write the desks own memory positions.",
+		Subcommands: []*cli.Command{{
+			Name:  "list",
+			Usage: "List the heights stored within the desks memory positions.",
+			Flags: append([]cli.Flag{}, sharedFlags...),
+			Action: func(context *cli.Context) error {
+				return commands.MemoryList(context, flags)
+			},
+		}, {
+			Name:      "set",
+			Usage:     "Store a height within a desk memory position.",
+			ArgsUsage: "[slot] [height]",
+			Flags:     append([]cli.Flag{}, sharedFlags...),
+			Action: func(context *cli.Context) error {
+				return commands.MemorySet(context, flags)
+			},
+		}, {
+			Name:      "go",
+			Usage:     "Move the desk to the height stored within a desk memory position.",
+			ArgsUsage: "[slot]",
+			Flags:     append([]cli.Flag{}, sharedFlags...),
+			Action: func(context *cli.Context) error {
+				return commands.MemoryGo(context, flags)
+			},
+		}, {
+			Name:  "sync",
+			Usage: "Write the sit and stand presets to the desks memory positions.",
+			Flags: append([]cli.Flag{&cli.BoolFlag{
+				Name:        "pull",
+				Usage:       "Update the presets from the desks memory positions instead",
+				Destination: &flags.Pull,
+			}}, sharedFlags...),
+			Action: func(context *cli.Context) error {
+				return commands.MemorySync(context, flags)
+			},
+		}},
+	}, {
+		Name:  "profile",
+		Usage: "Manage the user profiles sharing the desk.",
+		Subcommands: []*cli.Command{{
+			Name:  "list",
+			Usage: "List the user profiles, marking the active profile.",
+			Flags: append([]cli.Flag{}, sharedFlags...),
+			Action: func(context *cli.Context) error {
+				return commands.ProfileList(context, flags)
+			},
+		}, {
+			Name:      "add",
+			Usage:     "Add a user profile.",
+			ArgsUsage: "[name]",
+			Flags:     append([]cli.Flag{}, sharedFlags...),
+			Action: func(context *cli.Context) error {
+				return commands.ProfileAdd(context, flags)
+			},
+		}, {
+			Name:      "use",
+			Usage:     "Switch the active user profile, no name to stop using profiles.",
+			ArgsUsage: "[name]",
+			Flags:     append([]cli.Flag{}, sharedFlags...),
+			Action: func(context *cli.Context) error {
+				return commands.ProfileUse(context, flags)
+			},
+		}},
+	}, {
+		Name:  "rules",
+		Usage: "Move the desk when the configured rules are met.",
+		Subcommands: []*cli.Command{{
+			Name:  "run",
+			Usage: "Evaluate the rules until interrupted, moving the desk when a rule fires.",
+			Flags: append([]cli.Flag{}, sharedFlags...),
+			Action: func(context *cli.Context) error {
+				return commands.RulesRun(context, flags)
+			},
+		}, {
+			Name:  "explain",
+			Usage: "Explain which rule would fire right now and why the others would not.",
+			Flags: append([]cli.Flag{&cli.BoolFlag{
+				Name:        "json",
+				Usage:       "Print the decisions as JSON",
+				Destination: &flags.JSON,
+			}}, sharedFlags...),
+			Action: func(context *cli.Context) error {
+				return commands.RulesExplain(context, flags)
+			},
+		}},
+	}, {
+		Name:      "rename",
+		Usage:     "Rename the desk, changing the name it advertises.",
+		ArgsUsage: "[name]",
+		Flags:     append([]cli.Flag{}, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Rename(context, flags)
+		},
+	}, {
+		Name:  "info",
+		Usage: "Print what the desk reports about itself.",
+		Flags: append([]cli.Flag{&cli.BoolFlag{
+			Name:        "json",
+			Usage:       "Print the desk information as JSON",
+			Destination: &flags.JSON,
+		}}, sharedFlags...),
+		Action: func(context *cli.Context) error {
+			return commands.Info(context, flags)
+		},
 	}, {
 		Name:  "height",
 		Usage: "Get the current height of the desk.",
@@ -109,6 +378,8 @@ func main() {
 
 	err := app.Run(os.Args)
 	if err != nil {
-		log.Fatal(err)
+		log.Error(err)
 	}
+
+	os.Exit(commands.ExitCode(err))
 }
@@ -38,15 +38,22 @@ func UniqueScan(done chan struct{}) (chan *bluetooth.ScanResult, error) {
 
 	// Adapter scan runs in the background and will result in it running
 	// forever if not cancelled. This allows us to set up our own channel flow
-	// and pipe the data back.
+	// and pipe the data back. The output is only closed once the scan has
+	// returned, so no result can be sent on a closed channel.
 	go func() {
+		defer close(output)
+
 		_ = adapter.Scan(func(a *bluetooth.Adapter, result bluetooth.ScanResult) {
 			if _, exists := uniqueTracker[result.Address.String()]; exists {
 				return
 			}
 
 			uniqueTracker[result.Address.String()] = struct{}{}
-			output <- &result
+
+			select {
+			case output <- &result:
+			case <-done:
+			}
 		})
 	}()
 
@@ -56,7 +63,6 @@ func UniqueScan(done chan struct{}) (chan *bluetooth.ScanResult, error) {
 	go func() {
 		<-done
 
-		close(output)
 		if err := adapter.StopScan(); err != nil {
 			log.WithError(err).Error("failed to stop adapter scan")
 		}
@@ -0,0 +1,40 @@
+package blue
+
+import (
+	"errors"
+	"time"
+
+	"tinygo.org/x/bluetooth"
+)
+
+var ErrScanTimeout = errors.New("timed out waiting for device")
+
+// WaitForDevice scans for devices until one matches the given function or the
+// timeout is reached. Used to wait for a device to enter pairing mode and
+// start advertising.
+func WaitForDevice(timeout time.Duration, match func(result *bluetooth.ScanResult) bool) (*bluetooth.ScanResult, error) {
+	done := make(chan struct{})
+	defer close(done)
+
+	values, err := UniqueScan(done)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.After(timeout)
+
+	for {
+		select {
+		case value, ok := <-values:
+			if !ok {
+				return nil, ErrScanTimeout
+			}
+
+			if match(value) {
+				return value, nil
+			}
+		case <-deadline:
+			return nil, ErrScanTimeout
+		}
+	}
+}
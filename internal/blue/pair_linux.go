@@ -0,0 +1,66 @@
+package blue
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez/profile/agent"
+	log "github.com/sirupsen/logrus"
+	"tinygo.org/x/bluetooth"
+)
+
+// Pair bonds with the device at the given address and marks it as trusted, so
+// later connections can be made directly with ConnectToDevice. The device must
+// be discovered, e.g. by a scan, before it can be paired.
+//
+// Bonding is handled by BlueZ, a NoInputNoOutput agent is registered for the
+// duration of the pairing as desks do not support passkey entry.
+func Pair(address bluetooth.Addresser) error {
+	if enabledErr := adapter.Enable(); enabledErr != nil {
+		return enabledErr
+	}
+
+	defaultAdapter, err := api.GetDefaultAdapter()
+	if err != nil {
+		return fmt.Errorf("failed to get default adapter, %w", err)
+	}
+
+	device, err := defaultAdapter.GetDeviceByAddress(strings.ToUpper(address.String()))
+	if err != nil {
+		return fmt.Errorf("failed to find device %s, %w", address.String(), err)
+	}
+
+	if device == nil {
+		return fmt.Errorf("device %s has not been discovered", address.String())
+	}
+
+	if !device.Properties.Paired {
+		if conn, connErr := dbus.SystemBus(); connErr == nil {
+			pairingAgent := agent.NewSimpleAgent()
+
+			// A failure to register the agent is not fatal, an agent may already
+			// be registered by the system which can handle the pairing.
+			if agentErr := agent.ExposeAgent(conn, pairingAgent, agent.CapNoInputNoOutput, false); agentErr != nil {
+				log.WithError(agentErr).Debug("failed to register pairing agent")
+			} else {
+				defer func() {
+					if removeErr := agent.RemoveAgent(pairingAgent); removeErr != nil {
+						log.WithError(removeErr).Debug("failed to remove pairing agent")
+					}
+				}()
+			}
+		}
+
+		if err = device.Pair(); err != nil {
+			return fmt.Errorf("failed to pair with device %s, %w", address.String(), err)
+		}
+	}
+
+	if err = device.SetTrusted(true); err != nil {
+		return fmt.Errorf("failed to trust device %s, %w", address.String(), err)
+	}
+
+	return nil
+}
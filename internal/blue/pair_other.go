@@ -0,0 +1,15 @@
+//go:build !linux
+
+package blue
+
+import (
+	"errors"
+
+	"tinygo.org/x/bluetooth"
+)
+
+// Pair is only supported on Linux, other operating systems should pair the
+// device through the system bluetooth settings.
+func Pair(_ bluetooth.Addresser) error {
+	return errors.New("pairing is only supported on linux, pair the desk through the system settings")
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -13,6 +14,26 @@ var defaultConfig = Configuration{
 	LocalName:         "",
 	StandHeight:       1.12,
 	SitHeight:         0.74,
+	MoveMode:          "direction",
+	WakeUp:            true,
+	KeepAlive:         30 * time.Second,
+	IdleRelease:       5 * time.Minute,
+	HistoryPath:       "./.desk-history.jsonl",
+	WebhookDeadLetter: "./.desk-webhooks-failed.jsonl",
+	MemorySlots: MemorySlots{
+		Sit:   1,
+		Stand: 2,
+	},
+	Safety: Safety{
+		Action:  "stop",
+		Retries: 2,
+		Delay:   5 * time.Second,
+	},
+	DutyCycle: DutyCycle{
+		OnTime: 2 * time.Minute,
+		Window: 20 * time.Minute,
+		Action: "refuse",
+	},
 }
 
 type Configuration struct {
@@ -25,11 +46,214 @@ type Configuration struct {
 	// displaying if and when the user uses the configuration window.
 	LocalName string `json:"local_name" yaml:"local_name"`
 
+	// Paired is true once the desk has been bonded with this device through the
+	// pair command.
+	Paired bool `json:"paired" yaml:"paired"`
+
+	// UserID is the hex encoded user ID this device registered with the desk
+	// when pairing.
+	UserID string `json:"user_id" yaml:"user_id"`
+
 	// StandHeight is the configured stand height for the desk.
 	StandHeight float64 `json:"stand_height" yaml:"stand_height"`
 
 	// SitHeight is the configured sit height for the desk.
 	SitHeight float64 `json:"sit_height" yaml:"sit_height"`
+
+	// MoveMode determines how the desk is moved to a target, either
+	// "direction" (repeated up/down commands) or "reference" (the desk moves
+	// itself to the target, falling back to direction if unsupported).
+	MoveMode string `json:"move_mode" yaml:"move_mode"`
+
+	// ReferenceMinFirmware is the oldest firmware revision the "reference"
+	// move mode is tried on, older desks are moved by direction. Empty tries
+	// it on every desk.
+	ReferenceMinFirmware string `json:"reference_min_firmware" yaml:"reference_min_firmware"`
+
+	// WakeUp sends the wake-up command to the desk before every movement, so
+	// an idle desk does not ignore the first command.
+	WakeUp bool `json:"wake_up" yaml:"wake_up"`
+
+	// KeepAlive is how often a keep-alive is sent to the desk in long-running
+	// modes such as monitor. Zero disables the keep-alive.
+	KeepAlive time.Duration `json:"keep_alive" yaml:"keep_alive"`
+
+	// IdleRelease is how long the desk can be idle in long-running modes before
+	// the connection is dropped, allowing other devices such as the phone app
+	// to connect. The next request reconnects. Zero disables the release.
+	IdleRelease time.Duration `json:"idle_release" yaml:"idle_release"`
+
+	// HistoryPath is where desk events, such as moves and manual overrides,
+	// are recorded. Empty disables recording.
+	HistoryPath string `json:"history_path" yaml:"history_path"`
+
+	// MemorySlots maps the sit and stand presets to the desks own memory
+	// positions, used when syncing the presets with the desk.
+	MemorySlots MemorySlots `json:"memory_slots" yaml:"memory_slots"`
+
+	// Safety determines what happens after the desk safety feature stops a
+	// move, e.g. when colliding with a chair.
+	Safety Safety `json:"safety" yaml:"safety"`
+
+	// Body are the body measurements of the user, used to recommend the sit
+	// and stand heights with the ergonomics command.
+	Body Body `json:"body" yaml:"body"`
+
+	// MinHeight and MaxHeight are soft limits narrowing the heights the desk
+	// is moved to. Zero uses the limits of the desk.
+	MinHeight float64 `json:"min_height" yaml:"min_height"`
+	MaxHeight float64 `json:"max_height" yaml:"max_height"`
+
+	// DutyCycle limits how long the motor can run for, protecting it from
+	// automation moving the desk too often. Requires the history.
+	DutyCycle DutyCycle `json:"duty_cycle" yaml:"duty_cycle"`
+
+	// Hooks are shell commands run on desk events, passed the event as
+	// environment variables and as JSON on stdin.
+	Hooks []Hook `json:"hooks" yaml:"hooks,omitempty"`
+
+	// Webhooks are HTTP endpoints desk events are posted to as JSON.
+	Webhooks []Webhook `json:"webhooks" yaml:"webhooks,omitempty"`
+
+	// WebhookDeadLetter is where events that could not be delivered to a
+	// webhook are recorded. Empty drops them.
+	WebhookDeadLetter string `json:"webhook_dead_letter" yaml:"webhook_dead_letter"`
+
+	// Rules move the desk once their conditions are met, e.g. to stand after
+	// sitting for too long, evaluated while running the rules command.
+	Rules []Rule `json:"rules" yaml:"rules,omitempty"`
+
+	// Locked prevents the desk from being moved, toggled with the lock and
+	// unlock commands.
+	Locked bool `json:"locked" yaml:"locked"`
+
+	// QuietHours are daily windows during which the desk is locked, e.g.
+	// from 22:00 to 07:00.
+	QuietHours []QuietHours `json:"quiet_hours" yaml:"quiet_hours,omitempty"`
+
+	// Profile is the name of the active profile within Profiles, empty to use
+	// the values above.
+	Profile string `json:"profile" yaml:"profile"`
+
+	// Profiles are named user profiles sharing the desk, each overriding the
+	// presets, limits and body measurements above.
+	Profiles map[string]Profile `json:"profiles" yaml:"profiles,omitempty"`
+
+	// applied is the name of the profile applied through ApplyProfile, base
+	// the values it replaced, restored when saving, and effective the values
+	// once applied, telling changed values apart from inherited ones.
+	applied   string
+	base      Profile
+	effective Profile
+}
+
+// DutyCycle is the duty cycle the motor of the desk is rated for.
+type DutyCycle struct {
+	// OnTime is how long the motor can run for within the window. Zero
+	// disables the protection.
+	OnTime time.Duration `json:"on_time" yaml:"on_time"`
+
+	// Window is the period the on time applies to, e.g. 2 minutes on per 18
+	// minutes off is an on time of 2 minutes within a 20 minute window.
+	Window time.Duration `json:"window" yaml:"window"`
+
+	// Action is either "refuse" (fail the move) or "delay" (wait until the
+	// motor can run again) once the on time is used up.
+	Action string `json:"action" yaml:"action"`
+}
+
+// Hook is a shell command run on the given events, e.g. "target_reached", or
+// on all events when none are given.
+type Hook struct {
+	Events  []string `json:"events" yaml:"events,omitempty"`
+	Command string   `json:"command" yaml:"command"`
+}
+
+// Webhook is an HTTP endpoint the given events, or all events when none are
+// given, are posted to. Deliveries are signed with the secret when set.
+type Webhook struct {
+	URL    string   `json:"url" yaml:"url"`
+	Events []string `json:"events" yaml:"events,omitempty"`
+	Secret string   `json:"secret" yaml:"secret,omitempty"`
+}
+
+// Rule moves the desk to the sit or stand height once its condition is met.
+// The rule with the highest priority fires when several match.
+type Rule struct {
+	Name     string        `json:"name" yaml:"name"`
+	Priority int           `json:"priority" yaml:"priority,omitempty"`
+	When     RuleCondition `json:"when" yaml:"when"`
+
+	// Then is either "sit" or "stand".
+	Then string `json:"then" yaml:"then"`
+
+	// Cooldown is how long to wait after the rule fired before it can fire
+	// again.
+	Cooldown time.Duration `json:"cooldown" yaml:"cooldown,omitempty"`
+}
+
+// RuleCondition is met once the desk has been in the position for the
+// duration, within the optional daily window and days.
+type RuleCondition struct {
+	// Position is either "sitting" or "standing".
+	Position string        `json:"position" yaml:"position"`
+	For      time.Duration `json:"for" yaml:"for"`
+
+	// Start and End are the daily window in the 24-hour format 15:04, both
+	// empty for all day.
+	Start string `json:"start" yaml:"start,omitempty"`
+	End   string `json:"end" yaml:"end,omitempty"`
+
+	// Days are the days the rule applies on, e.g. "mon" or "weekdays", empty
+	// for every day.
+	Days []string `json:"days" yaml:"days,omitempty"`
+}
+
+// QuietHours is a daily window in local time, in the 24-hour format 15:04. A
+// window ending before it starts ends the next day.
+type QuietHours struct {
+	Start string `json:"start" yaml:"start"`
+	End   string `json:"end" yaml:"end"`
+}
+
+// Profile is a named user of the desk. Zero values use the value outside of
+// the profile.
+type Profile struct {
+	SitHeight   float64 `json:"sit_height" yaml:"sit_height,omitempty"`
+	StandHeight float64 `json:"stand_height" yaml:"stand_height,omitempty"`
+	MinHeight   float64 `json:"min_height" yaml:"min_height,omitempty"`
+	MaxHeight   float64 `json:"max_height" yaml:"max_height,omitempty"`
+	Body        Body    `json:"body" yaml:"body,omitempty"`
+}
+
+// MemorySlots defines which of the desks own memory positions (starting at 1)
+// the sit and stand presets are synced with. Zero disables syncing a preset.
+type MemorySlots struct {
+	Sit   int `json:"sit" yaml:"sit"`
+	Stand int `json:"stand" yaml:"stand"`
+}
+
+// Safety defines how a move recovers from the desk safety feature kicking in.
+type Safety struct {
+	// Action is either "stop" (give up), "retry" (move towards the target
+	// again after the delay) or "return" (move back to the start height).
+	Action string `json:"action" yaml:"action"`
+
+	// Retries is how many times the move is retried with the "retry" action.
+	Retries int `json:"retries" yaml:"retries"`
+
+	// Delay is how long to wait before retrying with the "retry" action.
+	Delay time.Duration `json:"delay" yaml:"delay"`
+}
+
+// Body are body measurements in meters, zero when unknown.
+type Body struct {
+	// Height is the height of the user, barefoot.
+	Height float64 `json:"height" yaml:"height"`
+
+	// ElbowHeight is the height of the elbow from the floor when standing in
+	// shoes with the upper arm hanging down.
+	ElbowHeight float64 `json:"elbow_height" yaml:"elbow_height"`
 }
 
 // Load attempts to pull the configuration from the given absolute path.
@@ -39,14 +263,16 @@ type Configuration struct {
 func Load(absolutePath string) (*Configuration, error) {
 	log.WithField("path", absolutePath).Debug("loading configuration")
 
-	var configuration Configuration
+	// Keys missing from the file keep their default value, so options added
+	// after the file was written are not left disabled.
+	configuration := defaultConfig
 	_, err := os.Stat(absolutePath)
 
 	// No reason to continue with the load operation and should use the default
 	// values if the file does not exist here. Otherwise the following is just
 	// going to fail anyway.
 	if os.IsNotExist(err) {
-		return &defaultConfig, nil
+		return &configuration, nil
 	}
 
 	yamlFile, err := os.ReadFile(absolutePath)
@@ -62,6 +288,107 @@ func Load(absolutePath string) (*Configuration, error) {
 	return &configuration, nil
 }
 
+// ApplyProfile replaces the presets, limits and body measurements with the
+// ones of the named profile, an empty name applies the active profile if set.
+// Saving the configuration afterwards saves any changes to them into the
+// profile.
+func (c *Configuration) ApplyProfile(name string) error {
+	if name == "" {
+		name = c.Profile
+	}
+
+	if name == "" || name == c.applied {
+		return nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+
+	if c.applied != "" {
+		c.setProfile(c.base)
+	}
+
+	c.applied, c.base = name, c.profile()
+
+	if profile.SitHeight != 0 {
+		c.SitHeight = profile.SitHeight
+	}
+
+	if profile.StandHeight != 0 {
+		c.StandHeight = profile.StandHeight
+	}
+
+	if profile.MinHeight != 0 {
+		c.MinHeight = profile.MinHeight
+	}
+
+	if profile.MaxHeight != 0 {
+		c.MaxHeight = profile.MaxHeight
+	}
+
+	if profile.Body != (Body{}) {
+		c.Body = profile.Body
+	}
+
+	c.effective = c.profile()
+	return nil
+}
+
+// AppliedProfile returns the name of the profile applied through
+// ApplyProfile, empty if none has been.
+func (c *Configuration) AppliedProfile() string {
+	return c.applied
+}
+
+func (c *Configuration) profile() Profile {
+	return Profile{
+		SitHeight:   c.SitHeight,
+		StandHeight: c.StandHeight,
+		MinHeight:   c.MinHeight,
+		MaxHeight:   c.MaxHeight,
+		Body:        c.Body,
+	}
+}
+
+func (c *Configuration) setProfile(profile Profile) {
+	c.SitHeight = profile.SitHeight
+	c.StandHeight = profile.StandHeight
+	c.MinHeight = profile.MinHeight
+	c.MaxHeight = profile.MaxHeight
+	c.Body = profile.Body
+}
+
+// savedProfile returns the applied profile with the values it sets itself and
+// the values changed since it was applied. Values inherited from outside of
+// the profile are left unset, so later changes to them still apply to it.
+func (c *Configuration) savedProfile() Profile {
+	profile, current := c.Profiles[c.applied], c.profile()
+
+	if profile.SitHeight != 0 || current.SitHeight != c.effective.SitHeight {
+		profile.SitHeight = current.SitHeight
+	}
+
+	if profile.StandHeight != 0 || current.StandHeight != c.effective.StandHeight {
+		profile.StandHeight = current.StandHeight
+	}
+
+	if profile.MinHeight != 0 || current.MinHeight != c.effective.MinHeight {
+		profile.MinHeight = current.MinHeight
+	}
+
+	if profile.MaxHeight != 0 || current.MaxHeight != c.effective.MaxHeight {
+		profile.MaxHeight = current.MaxHeight
+	}
+
+	if profile.Body != (Body{}) || current.Body != c.effective.Body {
+		profile.Body = current.Body
+	}
+
+	return profile
+}
+
 // Save attempts to save the configuration to the given absolute path.
 func (c *Configuration) Save(absolutePath string) error {
 	log.
@@ -69,7 +396,21 @@ func (c *Configuration) Save(absolutePath string) error {
 		WithField("configuration", c).
 		Debug("saving configuration")
 
-	bytes, err := yaml.Marshal(c)
+	// Changes to the applied profile are saved into the profile, keeping the
+	// values it replaced unchanged.
+	saved := *c
+
+	if c.applied != "" {
+		saved.Profiles = make(map[string]Profile, len(c.Profiles))
+		for name, profile := range c.Profiles {
+			saved.Profiles[name] = profile
+		}
+
+		saved.Profiles[c.applied] = c.savedProfile()
+		saved.setProfile(c.base)
+	}
+
+	bytes, err := yaml.Marshal(&saved)
 
 	if err != nil {
 		return fmt.Errorf("failed to marhsal configuration, %w", err)
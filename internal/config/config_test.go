@@ -0,0 +1,162 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "desk.yml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadMissingKeysUseDefaults(t *testing.T) {
+	path := writeConfig(t, "connection_address: AA:BB\nstand_height: 1.1\nmemory_slots:\n  sit: 3\n")
+
+	configuration, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if configuration.StandHeight != 1.1 || configuration.MemorySlots.Sit != 3 {
+		t.Errorf("expected the values within the file, got %+v", configuration)
+	}
+
+	if configuration.SitHeight != defaultConfig.SitHeight ||
+		configuration.MemorySlots.Stand != defaultConfig.MemorySlots.Stand ||
+		configuration.HistoryPath != defaultConfig.HistoryPath ||
+		configuration.DutyCycle != defaultConfig.DutyCycle {
+		t.Errorf("expected missing keys to use their defaults, got %+v", configuration)
+	}
+}
+
+func TestLoadExplicitZeroValues(t *testing.T) {
+	path := writeConfig(t, "history_path: \"\"\nmemory_slots:\n  sit: 0\n  stand: 0\n")
+
+	configuration, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if configuration.HistoryPath != "" || configuration.MemorySlots != (MemorySlots{}) {
+		t.Errorf("expected explicit zero values to be kept, got %+v", configuration)
+	}
+}
+
+func TestLoadMissingFileDoesNotShareDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.yml")
+
+	first, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.SitHeight = 0.9
+
+	second, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second.SitHeight != defaultConfig.SitHeight {
+		t.Errorf("expected the defaults to be unchanged, got %.2f", second.SitHeight)
+	}
+}
+
+const profileConfig = `
+sit_height: 0.74
+stand_height: 1.12
+max_height: 1.25
+profile: alex
+profiles:
+  alex:
+    sit_height: 0.68
+`
+
+func TestApplyProfile(t *testing.T) {
+	configuration, err := Load(writeConfig(t, profileConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = configuration.ApplyProfile(""); err != nil {
+		t.Fatal(err)
+	}
+
+	if configuration.AppliedProfile() != "alex" {
+		t.Errorf("expected the active profile to be applied, got %q", configuration.AppliedProfile())
+	}
+
+	if configuration.SitHeight != 0.68 || configuration.StandHeight != 1.12 || configuration.MaxHeight != 1.25 {
+		t.Errorf("expected the profile over the inherited values, got %+v", configuration.profile())
+	}
+
+	if err = configuration.ApplyProfile("bob"); err == nil {
+		t.Error("expected an unknown profile to be rejected")
+	}
+}
+
+func TestSaveProfileKeepsInheritedValues(t *testing.T) {
+	path := writeConfig(t, profileConfig)
+
+	configuration, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = configuration.ApplyProfile(""); err != nil {
+		t.Fatal(err)
+	}
+
+	configuration.SitHeight = 0.70
+	configuration.Body = Body{Height: 1.80}
+
+	if err = configuration.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Profile{SitHeight: 0.70, Body: Body{Height: 1.80}}
+	if profile := saved.Profiles["alex"]; profile != expected {
+		t.Errorf("expected only the changed values within the profile, got %+v", profile)
+	}
+
+	if saved.SitHeight != 0.74 || saved.StandHeight != 1.12 || saved.MaxHeight != 1.25 || saved.Body != (Body{}) {
+		t.Errorf("expected the values outside of the profile to be unchanged, got %+v", saved.profile())
+	}
+
+	// Later changes outside of the profile still reach the profile.
+	saved.StandHeight = 1.05
+	if err = saved.ApplyProfile(""); err != nil {
+		t.Fatal(err)
+	}
+
+	if saved.StandHeight != 1.05 {
+		t.Errorf("expected the profile to inherit the new stand height, got %.2f", saved.StandHeight)
+	}
+}
+
+func TestLoadPartialSectionUsesDefaults(t *testing.T) {
+	configuration, err := Load(writeConfig(t, "duty_cycle:\n  action: delay\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := defaultConfig.DutyCycle
+	expected.Action = "delay"
+
+	if configuration.DutyCycle != expected {
+		t.Errorf("expected %+v, got %+v", expected, configuration.DutyCycle)
+	}
+}
@@ -0,0 +1,127 @@
+package desk
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Close disconnects from the desk, allowing other devices such as the phone
+// app to connect to it. The desk only accepts a single connection at a time.
+func (d *Desk) Close() error {
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
+
+	if d.idleDone != nil {
+		close(d.idleDone)
+		d.idleDone = nil
+	}
+
+	d.released = false
+	err := d.disconnectLocked()
+	d.resubscribe = false
+
+	return err
+}
+
+// disconnectLocked disconnects from the desk and drops all discovered services
+// and characteristics. connMu must be held.
+func (d *Desk) disconnectLocked() error {
+	if d.device == nil {
+		return nil
+	}
+
+	log.Debugf("disconnecting from %s", d.Name())
+
+	err := d.device.Disconnect()
+
+	d.device = nil
+	d.serviceCharacteristics = nil
+	d.dpg = nil
+	d.resetNotificationsLocked()
+
+	d.emit(Event{Type: EventDisconnected, Height: d.lastReading().height})
+
+	if err != nil {
+		return fmt.Errorf("failed to disconnect from desk, %w", err)
+	}
+
+	return nil
+}
+
+// startIdleRelease starts releasing the connection once the desk has been idle
+// for the configured idle release duration, if one is configured.
+func (d *Desk) startIdleRelease() {
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
+
+	if d.idleRelease <= 0 || d.idleDone != nil {
+		return
+	}
+
+	d.idleDone = make(chan struct{})
+	go d.idleReleaseLoop(d.idleDone)
+}
+
+func (d *Desk) idleReleaseLoop(done <-chan struct{}) {
+	ticker := time.NewTicker(max(d.idleRelease/10, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			d.releaseIfIdle()
+		}
+	}
+}
+
+// releaseIfIdle disconnects from the desk if there has been no activity for
+// the idle release duration. The next request to the desk reconnects.
+//
+// Height subscribers stay registered, e.g. monitor or the dashboard, their
+// notifications are re-enabled once the next request reconnects. The desk is
+// never idle while it is being moved, as the move relies on them.
+func (d *Desk) releaseIfIdle() {
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
+
+	if d.device == nil || time.Since(d.lastActivity) < d.idleRelease || d.inUse() {
+		return
+	}
+
+	if err := d.disconnectLocked(); err != nil {
+		log.WithError(err).Warn("failed to release idle desk connection")
+	}
+
+	d.released = true
+	log.Infof("released connection to %s after %s idle", d.Name(), d.idleRelease)
+}
+
+// inUse returns true if the desk is being moved.
+func (d *Desk) inUse() bool {
+	d.moves.mu.Lock()
+	defer d.moves.mu.Unlock()
+	return d.moves.running
+}
+
+// withoutActivity runs the function without it counting as activity towards
+// the idle release. Skipped entirely if the connection has been released, so
+// it does not trigger a reconnect.
+func (d *Desk) withoutActivity(fn func()) {
+	d.connMu.Lock()
+	released, lastActivity := d.released, d.lastActivity
+	d.connMu.Unlock()
+
+	if released {
+		return
+	}
+
+	fn()
+
+	d.connMu.Lock()
+	d.lastActivity = lastActivity
+	d.connMu.Unlock()
+}
@@ -0,0 +1,113 @@
+package desk
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestIdleReleaseWithSubscriber releases the connection while the height is
+// followed, re-enabling the notifications once reconnected.
+func TestIdleReleaseWithSubscriber(t *testing.T) {
+	f := newFakeDesk(0.75)
+	d := newConnectedTestDesk(t, f, WithIdleRelease(time.Minute))
+
+	var mu sync.Mutex
+	var heights []float64
+
+	unsubscribe, err := d.SubscribeHeight(func(height, _ float64) {
+		mu.Lock()
+		defer mu.Unlock()
+		heights = append(heights, height)
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer unsubscribe()
+
+	d.connMu.Lock()
+	d.lastActivity = time.Now().Add(-2 * time.Minute)
+	d.connMu.Unlock()
+
+	d.releaseIfIdle()
+
+	f.mu.Lock()
+	connected := f.connected
+	f.mu.Unlock()
+
+	if connected {
+		t.Fatal("expected the connection to be released while subscribed to")
+	}
+
+	if err = d.MoveToTarget(0.80); err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	connected, dials := f.connected, f.dials
+	f.mu.Unlock()
+
+	if !connected || dials != 2 {
+		t.Fatalf("expected the move to reconnect, got %d dials", dials)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(heights) == 0 || heights[len(heights)-1] < 0.795 {
+		t.Errorf("expected the subscriber to follow the move after reconnecting, got %v", heights)
+	}
+}
+
+func TestIdleReleaseWhileMoving(t *testing.T) {
+	f := newFakeDesk(0.75)
+	d := newConnectedTestDesk(t, f, WithIdleRelease(time.Minute))
+
+	request := d.startMove(1.0, "")
+	defer func() {
+		d.cancelMove(request, ErrMoveStopped)
+		<-d.movesIdle()
+	}()
+
+	d.connMu.Lock()
+	d.lastActivity = time.Now().Add(-2 * time.Minute)
+	d.connMu.Unlock()
+
+	d.releaseIfIdle()
+
+	d.connMu.Lock()
+	released := d.released
+	d.connMu.Unlock()
+
+	if released {
+		t.Error("expected the connection to be kept while moving")
+	}
+}
+
+func TestInUseWhileMoving(t *testing.T) {
+	d := newTestDesk(newFakeDesk(0.75))
+
+	request := d.startMove(1.0, "")
+
+	if !d.inUse() {
+		t.Error("expected the desk to be in use while moving")
+	}
+
+	d.cancelMove(request, ErrMoveStopped)
+	<-d.movesIdle()
+
+	if d.inUse() {
+		t.Error("expected the desk not to be in use once stopped")
+	}
+}
+
+func TestStopWithoutCharacteristics(t *testing.T) {
+	d, _ := NewDesk("", "", false)
+
+	if err := d.Stop(); !errors.Is(err, ErrCharacteristicMissing) {
+		t.Errorf("expected %v, got %v", ErrCharacteristicMissing, err)
+	}
+}
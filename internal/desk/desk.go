@@ -1,8 +1,6 @@
 package desk
 
 import (
-	"errors"
-	"fmt"
 	"math"
 	"os"
 	"os/signal"
@@ -15,23 +13,31 @@ import (
 	"tinygo.org/x/bluetooth"
 
 	"idasen-desk/internal/blue"
+	"idasen-desk/internal/desk/dpg"
 )
 
 var (
 	UuidHeight         = blue.MustParseUUID("99fa0021-338a-1024-8a49-009c0215f78a")
 	UuidCommand        = blue.MustParseUUID("99fa0002-338a-1024-8a49-009c0215f78a")
 	UuidReferenceInput = blue.MustParseUUID("99fa0031-338a-1024-8a49-009c0215f78a")
+	UuidDPG            = blue.MustParseUUID("99fa0011-338a-1024-8a49-009c0215f78a")
 
 	// UuidAdvSvc - Not currently used but can be used to determine if the given device is a
 	// desk or not. If it is a desk then the deskService (services_uuid) list will contain this uuid.
 	UuidAdvSvc = blue.MustParseUUID("99fa0001-338a-1024-8a49-009c0215f78a")
 )
 
+// MaxHeight and MinHeight are the limits of the IDÅSEN desk, used when the
+// desk does not report its own base offset.
 const (
 	MaxHeight = 1.27
 	MinHeight = 0.62
 )
 
+// travelRange is the assumed distance the desk can travel above its base
+// offset, the travel of the IDÅSEN desk.
+const travelRange = MaxHeight - MinHeight
+
 type Direction int
 
 const (
@@ -40,24 +46,107 @@ const (
 	DOWN
 )
 
-type Desk struct {
-	name    string
-	address string
+func (d Direction) String() string {
+	switch d {
+	case UP:
+		return "up"
+	case DOWN:
+		return "down"
+	case UNKNOWN:
+	}
+
+	return "unknown"
+}
+
+// Characteristic is the transport used to communicate with a single
+// characteristic of the desk. Satisfied by *bluetooth.DeviceCharacteristic and
+// replaceable with a fake through WithCharacteristics.
+type Characteristic interface {
+	UUID() bluetooth.UUID
+	Read(data []byte) (int, error)
+	WriteWithoutResponse(p []byte) (int, error)
+	EnableNotifications(callback func(buf []byte)) error
+}
+
+// connection is an open connection to the desk. Satisfied by
+// *bluetooth.Device.
+type connection interface {
+	Disconnect() error
+}
 
-	device                 *bluetooth.Device
-	deskService            []bluetooth.DeviceService
-	serviceCharacteristics []bluetooth.DeviceCharacteristic
+type Desk struct {
+	name     string
+	address  string
+	moveMode MoveMode
+
+	// referenceMinFirmware is the oldest firmware the reference input is
+	// tried on, empty for any.
+	referenceMinFirmware string
+
+	wakeUp            bool
+	keepAliveInterval time.Duration
+	eventHandlers     []func(event Event)
+	safety            SafetyPolicy
+
+	// minHeight and maxHeight are the limits of the desk, using the base
+	// offset reported by the desk when available.
+	minHeight float64
+	maxHeight float64
+
+	// softMinHeight and softMaxHeight narrow the heights the desk is moved to,
+	// zero when not set.
+	softMinHeight float64
+	softMaxHeight float64
+
+	// profile is the user profile events are attributed to.
+	profile string
+
+	// guards refuse movement of the desk when one returns an error, move
+	// guards are also given the distance of the move. source describes who is
+	// controlling the desk when they do.
+	guards     []func() error
+	moveGuards []func(distance float64) error
+	source     string
+
+	// dial connects to the desk, returning the connection and the
+	// characteristics of all of its services.
+	dial                   func() (connection, []Characteristic, error)
+	device                 connection
+	serviceCharacteristics []Characteristic
+
+	dpg *dpg.Client
+
+	// connMu guards the connection state, which can be released by the idle
+	// release and re-established by the next request.
+	connMu       sync.Mutex
+	idleRelease  time.Duration
+	idleDone     chan struct{}
+	released     bool
+	lastActivity time.Time
+	resubscribe  bool
+
+	heights heightNotifier
+	moves   moveQueue
 }
 
-func NewDesk(name, address string, connect bool) (*Desk, error) {
+func NewDesk(name, address string, connect bool, opts ...Option) (*Desk, error) {
 	desk := &Desk{
 		name:                   name,
 		address:                address,
+		moveMode:               MoveModeDirection,
+		safety:                 SafetyPolicy{Action: SafetyActionStop},
+		minHeight:              MinHeight,
+		maxHeight:              MaxHeight,
 		device:                 nil,
-		deskService:            nil,
 		serviceCharacteristics: nil,
 	}
 
+	desk.dial = desk.dialBluetooth
+
+	for _, opt := range opts {
+		opt(desk)
+	}
+
 	if connect {
 		return desk, desk.Connect()
 	}
@@ -66,25 +155,133 @@ func NewDesk(name, address string, connect bool) (*Desk, error) {
 }
 
 // Connect will attempt to connect to the desk via bluetooth.
-func (d *Desk) Connect() (err error) {
-	mac, _ := bluetooth.ParseMAC(d.address)
-	address := bluetooth.Address{MACAddress: bluetooth.MACAddress{MAC: mac}}
+func (d *Desk) Connect() error {
+	d.connMu.Lock()
+	err := d.connectLocked()
+	d.connMu.Unlock()
 
-	if d.device, err = blue.ConnectToDevice(address); err != nil {
+	if err != nil {
 		return err
 	}
 
-	if d.deskService, err = d.device.DiscoverServices(nil); err != nil {
+	d.loadLimits()
+	d.startIdleRelease()
+
+	_, err = d.GetHeight()
+	return err
+}
+
+// connectLocked connects to the desk and discovers all of its services and
+// characteristics. connMu must be held.
+func (d *Desk) connectLocked() (err error) {
+	if d.address == "" {
+		return ErrNotConfigured
+	}
+
+	if d.device, d.serviceCharacteristics, err = d.dial(); err != nil {
 		return err
 	}
 
-	for _, service := range d.deskService {
+	d.released = false
+	d.lastActivity = time.Now()
+	d.resubscribeLocked()
+
+	d.emit(Event{Type: EventConnected, Height: d.lastReading().height})
+
+	return nil
+}
+
+// dialBluetooth connects to the desk over bluetooth and discovers all of its
+// services and characteristics.
+func (d *Desk) dialBluetooth() (connection, []Characteristic, error) {
+	mac, _ := bluetooth.ParseMAC(d.address)
+	address := bluetooth.Address{MACAddress: bluetooth.MACAddress{MAC: mac}}
+
+	device, err := blue.ConnectToDevice(address)
+	if err != nil {
+		return nil, nil, &ConnectError{Address: d.address, Err: err}
+	}
+
+	services, err := device.DiscoverServices(nil)
+	if err != nil {
+		return device, nil, &ConnectError{Address: d.address, Err: err}
+	}
+
+	var discovered []Characteristic
+
+	for _, service := range services {
 		characteristics, _ := service.DiscoverCharacteristics(nil)
-		d.serviceCharacteristics = append(d.serviceCharacteristics, characteristics...)
+
+		for i := range characteristics {
+			discovered = append(discovered, &characteristics[i])
+		}
 	}
 
-	_, err = d.GetHeight()
-	return err
+	return device, discovered, nil
+}
+
+// loadLimits queries the base offset of the desk and uses it for all height
+// conversions and validation. None of the DPG commands report the travel range
+// of the desk, so the maximum height assumes the travel of the IDÅSEN desk on
+// top of the reported offset.
+//
+// Falls back to MinHeight and MaxHeight if the desk does not report an offset.
+func (d *Desk) loadLimits() {
+	client, err := d.DPG()
+	if err != nil {
+		log.WithError(err).Debug("desk does not support dpg, using default limits")
+		return
+	}
+
+	offset, ok, err := client.DeskOffset()
+	if err != nil || !ok || offset == 0 {
+		log.WithError(err).Debug("desk did not report an offset, using default limits")
+		return
+	}
+
+	d.minHeight = offset.Meters()
+	d.maxHeight = d.minHeight + travelRange
+
+	log.Debugf("desk limits: min=%.4f, max=%.4f", d.minHeight, d.maxHeight)
+}
+
+// Limits returns the minimum and maximum height of the desk.
+func (d *Desk) Limits() (minHeight, maxHeight float64) {
+	return d.minHeight, d.maxHeight
+}
+
+// MoveLimits returns the minimum and maximum height the desk is moved to, the
+// limits of the desk narrowed by the soft limits set through WithSoftLimits.
+func (d *Desk) MoveLimits() (minHeight, maxHeight float64) {
+	return d.moveLimits(d.softMinHeight, d.softMaxHeight)
+}
+
+// moveLimits returns the limits of the desk narrowed by the given soft limits,
+// zero for no limit.
+func (d *Desk) moveLimits(softMinHeight, softMaxHeight float64) (minHeight, maxHeight float64) {
+	minHeight, maxHeight = d.minHeight, d.maxHeight
+
+	if softMinHeight != 0 {
+		minHeight = math.Max(minHeight, softMinHeight)
+	}
+
+	if softMaxHeight != 0 {
+		maxHeight = math.Min(maxHeight, softMaxHeight)
+	}
+
+	return minHeight, maxHeight
+}
+
+// validateHeight ensures the given height is within the move limits of the
+// desk.
+func (d *Desk) validateHeight(name string, height float64) error {
+	minHeight, maxHeight := d.MoveLimits()
+
+	if height > maxHeight || height < minHeight {
+		return &RangeError{Name: name, Height: height, Min: minHeight, Max: maxHeight}
+	}
+
+	return nil
 }
 
 func (d *Desk) Name() string {
@@ -95,19 +292,39 @@ func (d *Desk) Name() string {
 	return d.name
 }
 
+// DPG returns the client used to send Linak DPG commands to the desk. The
+// client is created on first use and shared for the lifetime of the desk.
+func (d *Desk) DPG() (*dpg.Client, error) {
+	characteristic := d.getCharacteristic(UuidDPG)
+
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
+
+	if d.dpg != nil {
+		return d.dpg, nil
+	}
+
+	if characteristic == nil {
+		return nil, &CharacteristicError{UUID: UuidDPG}
+	}
+
+	d.dpg = dpg.New(characteristic)
+	return d.dpg, nil
+}
+
 // GetHeight returns the current height of the desk by direct 1:1 communication
 // and no by a notification. This includes some delay.
 func (d *Desk) GetHeight() (float64, error) {
 	characteristic := d.getCharacteristic(UuidHeight)
 
 	if characteristic == nil {
-		return 0, fmt.Errorf("does not have required characteristic: %s", UuidHeight.String())
+		return 0, &CharacteristicError{UUID: UuidHeight}
 	}
 
 	data := make([]byte, 4)
 	_, err := characteristic.Read(data)
 
-	return bytesToMeters(data), err
+	return d.bytesToMeters(data), err
 }
 
 // Stop tells the desk to stop moving.
@@ -115,7 +332,14 @@ func (d *Desk) GetHeight() (float64, error) {
 // The desk does not stop automatically unless the safety kicks in.
 func (d *Desk) Stop() error {
 	commandChar := d.getCharacteristic(UuidCommand)
+	if commandChar == nil {
+		return &CharacteristicError{UUID: UuidCommand}
+	}
+
 	referenceChar := d.getCharacteristic(UuidReferenceInput)
+	if referenceChar == nil {
+		return &CharacteristicError{UUID: UuidReferenceInput}
+	}
 
 	commandStop := []byte{0xFF, 0x00}
 	commandRefInput := []byte{0x01, 0x80}
@@ -137,158 +361,75 @@ func (d *Desk) Stop() error {
 
 // Monitor purely listens to the notification events fired by the desk and
 // prints them to the display. Existing only on the control+c calls or hard
-// exits.
+// exits. A keep-alive is sent while monitoring if configured, and
+// EventManualMove is emitted when the desk is moved with its buttons.
 func (d *Desk) Monitor() error {
-	heightCharacteristic := d.getCharacteristic(UuidHeight)
-
-	if err := heightCharacteristic.EnableNotifications(func(buf []byte) {
-		log.Infof("%f", bytesToMeters(buf))
-	}); err != nil {
+	if err := d.enableHeightNotifications(); err != nil {
 		return err
 	}
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	settled := d.lastReading().height
 
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	<-c
+	unsubscribe, err := d.SubscribeHeight(func(height, speed float64) {
+		log.Infof("%f", height)
 
-	return nil
-}
-
-// MoveToTarget move the desk to the specified target float value. Within the
-// constraints of the device min value and max value.
-func (d *Desk) MoveToTarget(target float64) error {
-	if target > MaxHeight {
-		return fmt.Errorf("provided target (%.2f) exceeds maximum height (%.2f)", target, MaxHeight)
-	} else if target < MinHeight {
-		return fmt.Errorf("provided target (%.2f) is below minimum height (%.2f)", target, MinHeight)
-	}
+		// The desk reports a speed of zero once it stops, a change in height
+		// without an automated move running was made with the buttons.
+		if speed == 0 && math.Abs(height-settled) > 0.005 && d.activeMove() == nil {
+			d.emit(Event{
+				Type:      EventManualMove,
+				Height:    height,
+				Direction: directionTo(settled, height).String(),
+			})
+		}
 
-	heightCharacteristic := d.getCharacteristic(UuidHeight)
-	currentHeight, err := d.GetHeight()
+		if speed == 0 {
+			settled = height
+		}
+	})
 
 	if err != nil {
-		return fmt.Errorf("failed to get desk height, %w", err)
+		return err
 	}
 
-	previousHeight := currentHeight
-	willMoveUp := target > previousHeight
+	defer unsubscribe()
 
-	log.Infof("moving desk from %.2f to %.2f", previousHeight, target)
+	done := make(chan struct{})
+	defer close(done)
 
-	var mu sync.RWMutex
-	getHeight := func() float64 {
-		mu.RLock()
-		defer mu.RUnlock()
-		return currentHeight
-	}
+	go d.KeepAlive(done)
 
-	setHeight := func(value float64) {
-		mu.Lock()
-		defer mu.Unlock()
-		currentHeight = value
-	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
 
-	// Use the implemented notification characteristics to get real time
-	// updates on the position of the desk. Allowing the loop iteration to only
-	// care about directional control.
-	if err = heightCharacteristic.EnableNotifications(func(buf []byte) {
-		log.Debugf("desk height notification: %f", bytesToMeters(buf))
-		setHeight(bytesToMeters(buf))
-	}); err != nil {
-		return fmt.Errorf("failed to configure desk hight notifications, %w", err)
-	}
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	<-c
 
-	for {
-		loopHeight := getHeight()
-
-		differenceRaw := target - loopHeight
-		differenceAbs := math.Abs(differenceRaw)
-
-		log.Debugf("target=%f, current_height=%f previous_height=%f, difference=%f",
-			target, loopHeight, previousHeight, differenceRaw)
-
-		// The device has a moving action to protect the user if it applies
-		// pressure to something when moving. This will result in the desk
-		// moving in the opposite direction when the device detects something.
-		// Moving out th way. If we detect this, stop.
-		//
-		// Only if our difference is not nothing, meaning we are not doing a
-		// minor correction.
-		if (loopHeight < previousHeight && willMoveUp ||
-			loopHeight > previousHeight && !willMoveUp) &&
-			differenceAbs > 0.010 {
-			log.Errorf("stopped moving because desk safety feature kicked in.")
-			return deskMoveSafetyKickIn
-		}
+	return nil
+}
 
-		// If we're either less than 10mm then we need to stop every iteration
-		// so that we don't overshoot
-		if differenceAbs < 0.010 {
-			log.Debugf("hit differnce made: height: %f - difference: %f",
-				loopHeight, differenceRaw)
+// getCharacteristic returns the characteristic with the given uuid, or nil if
+// the desk does not have it. All communication with the desk goes through here,
+// so it also marks the desk as active and reconnects if the connection was
+// released after being idle.
+func (d *Desk) getCharacteristic(uuid bluetooth.UUID) Characteristic {
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
 
-			if stopErr := d.Stop(); stopErr != nil {
-				return stopErr
-			}
-		}
+	if d.released {
+		log.Info("reconnecting to desk after idle release")
 
-		// If we are within our tolerance for moving the desk then we can go and
-		// stop. Additionally ensure to stop first to keep in line with our
-		// tolerance. Otherwise, a shift in the difference could occur when
-		// pulling the final destination.
-		//
-		// within 5mm
-		if differenceAbs <= 0.005 {
-			if stopErr := d.Stop(); stopErr != nil {
-				return stopErr
-			}
-
-			// Sleep for the duration of a possible upper limit of a step
-			// duration. This duration was determined from a single `MOVE`
-			// operation.
-			time.Sleep(time.Millisecond * 100)
-			log.Infof("reached target of %.3f, actual: %.3f", target, getHeight())
+		if err := d.connectLocked(); err != nil {
+			log.WithError(err).Error("failed to reconnect to desk")
 			return nil
 		}
-
-		operation := UP
-		if differenceRaw < 0.0 {
-			operation = DOWN
-		}
-
-		// Attempt to move into the correct direction, if it faults, attempt to
-		// stop and return the errors.
-		if err = d.MoveDirection(operation); err != nil {
-			return errors.Join(err, d.Stop())
-		}
-
-		previousHeight = loopHeight
-	}
-}
-
-// MoveDirection Based on the provided direction, the desk will be told to start
-// moving up or start moving down. A move action will only occur for a 1-second
-// interval, which is configured by the desk.
-func (d *Desk) MoveDirection(direction Direction) error {
-	actionArgs := []uint8{0x47, 0x00}
-
-	if direction == DOWN {
-		actionArgs = []uint8{0x46, 0x00}
 	}
 
-	if _, err := d.getCharacteristic(UuidCommand).WriteWithoutResponse(actionArgs); err != nil {
-		return fmt.Errorf("%s: %w", bluetoothError.Error(), err)
-	}
+	d.lastActivity = time.Now()
 
-	return nil
-}
-
-func (d *Desk) getCharacteristic(uuid bluetooth.UUID) *bluetooth.DeviceCharacteristic {
 	for _, characteristic := range d.serviceCharacteristics {
 		if characteristic.UUID() == uuid {
-			return &characteristic
+			return characteristic
 		}
 	}
 
@@ -296,7 +437,7 @@ func (d *Desk) getCharacteristic(uuid bluetooth.UUID) *bluetooth.DeviceCharacter
 }
 
 // Converts the raw height response from the desk into meters.
-func bytesToMeters(raw []uint8) float64 {
+func (d *Desk) bytesToMeters(raw []uint8) float64 {
 	var highByte int
 	var lowByte int
 
@@ -304,5 +445,22 @@ func bytesToMeters(raw []uint8) float64 {
 	lowByte = int(raw[0])
 
 	number := (highByte << 8) + lowByte
-	return (float64(number) / 10000.0) + MinHeight
+	return (float64(number) / 10000.0) + d.minHeight
+}
+
+// Converts the speed part of the raw height notification into meters per
+// second. The desk reports speed in hundredths of a millimetre per second.
+func bytesToSpeed(raw []uint8) float64 {
+	if len(raw) < 4 {
+		return 0
+	}
+
+	speed := int16(uint16(raw[3])<<8 | uint16(raw[2]))
+	return float64(speed) / 100000.0
+}
+
+// Converts meters into the raw value the desk accepts as a reference input.
+func (d *Desk) metersToBytes(meters float64) []uint8 {
+	number := uint16(math.Round((meters - d.minHeight) * 10000.0))
+	return []uint8{uint8(number & 0xFF), uint8(number >> 8)}
 }
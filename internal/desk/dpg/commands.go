@@ -0,0 +1,74 @@
+package dpg
+
+import "fmt"
+
+// Capabilities reads the features supported by the desk controller.
+func (c *Client) Capabilities() (Capabilities, error) {
+	payload, err := c.Read(CommandCapabilities)
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	return ParseCapabilities(payload)
+}
+
+// ProductInfo reads the product information reported by the desk.
+func (c *Client) ProductInfo() (ProductInfo, error) {
+	payload, err := c.Read(CommandProductInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// DeskOffset reads the base offset of the desk, the height of the desk at its
+// lowest position. The returned bool is false if the desk does not report one.
+func (c *Client) DeskOffset() (Position, bool, error) {
+	payload, err := c.Read(CommandDeskOffset)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return ParsePosition(payload)
+}
+
+// MemoryPosition reads the position stored in the given memory slot, starting
+// at 1. The returned bool is false if nothing is stored within the slot.
+func (c *Client) MemoryPosition(slot int) (Position, bool, error) {
+	command, err := memoryCommand(slot)
+	if err != nil {
+		return 0, false, err
+	}
+
+	payload, err := c.Read(command)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return ParsePosition(payload)
+}
+
+// SetMemoryPosition stores the position within the given memory slot,
+// starting at 1.
+func (c *Client) SetMemoryPosition(slot int, position Position) error {
+	command, err := memoryCommand(slot)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(command, EncodePosition(position))
+}
+
+func memoryCommand(slot int) (Command, error) {
+	if slot < 1 || slot > MaxMemorySlots {
+		return 0, fmt.Errorf("memory slot must be between 1 and %d, got %d", MaxMemorySlots, slot)
+	}
+
+	return CommandMemoryPosition1 + Command(slot-1), nil
+}
+
+// SetUserID registers the controller with the desk under the given user ID.
+func (c *Client) SetUserID(id []byte) error {
+	return c.Write(CommandUserID, id)
+}
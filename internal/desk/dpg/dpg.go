@@ -0,0 +1,159 @@
+// Package dpg implements the Linak DPG (desk panel) protocol, used to query and
+// configure the desk controller: capabilities, product information, the desk
+// offset, memory positions and user settings.
+//
+// A command is written to the DPG characteristic and the desk replies with a
+// notification on the same characteristic. Reads are sent as
+// [0x7F, command, 0x00] and writes as [0x7F, command, 0x80, length, data...].
+// Responses start with 0x01 when successful followed by the payload length and
+// the payload itself.
+package dpg
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Command is a single DPG command identifier.
+type Command uint8
+
+const (
+	CommandProductInfo     Command = 0x08
+	CommandCapabilities    Command = 0x80
+	CommandDeskOffset      Command = 0x81
+	CommandUserID          Command = 0x86
+	CommandReminderSetting Command = 0x88
+	CommandMemoryPosition1 Command = 0x89
+	CommandMemoryPosition2 Command = 0x8A
+	CommandMemoryPosition3 Command = 0x8B
+	CommandMemoryPosition4 Command = 0x8C
+)
+
+const (
+	headerRequest  = 0x7F
+	headerResponse = 0x01
+
+	flagRead  = 0x00
+	flagWrite = 0x80
+)
+
+// MaxMemorySlots is the maximum number of memory positions a controller can
+// store, as addressed by the memory position commands.
+const MaxMemorySlots = 4
+
+// DefaultTimeout is how long to wait for the desk to respond to a command.
+const DefaultTimeout = 2 * time.Second
+
+var (
+	ErrTimeout       = errors.New("dpg: timed out waiting for desk response")
+	ErrCommandFailed = errors.New("dpg: desk rejected command")
+	ErrShortResponse = errors.New("dpg: response too short")
+)
+
+// Characteristic is the subset of a bluetooth characteristic required to talk
+// to the DPG characteristic.
+type Characteristic interface {
+	WriteWithoutResponse(p []byte) (n int, err error)
+	EnableNotifications(callback func(buf []byte)) error
+}
+
+// Client sends DPG commands and waits for their responses. Only a single
+// command can be in flight at any given time, concurrent calls are serialised.
+type Client struct {
+	characteristic Characteristic
+	timeout        time.Duration
+
+	mu        sync.Mutex
+	enabled   bool
+	responses chan []byte
+}
+
+// New creates a new client communicating over the given DPG characteristic.
+func New(characteristic Characteristic) *Client {
+	return &Client{
+		characteristic: characteristic,
+		timeout:        DefaultTimeout,
+		responses:      make(chan []byte, 1),
+	}
+}
+
+// Read sends a read request for the given command and returns the response
+// payload.
+func (c *Client) Read(command Command) ([]byte, error) {
+	return c.send([]byte{headerRequest, uint8(command), flagRead})
+}
+
+// Write sends the data for the given command to the desk.
+func (c *Client) Write(command Command, data []byte) error {
+	request := append([]byte{headerRequest, uint8(command), flagWrite, uint8(len(data))}, data...)
+	_, err := c.send(request)
+	return err
+}
+
+func (c *Client) send(request []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.enabled {
+		if err := c.characteristic.EnableNotifications(c.handleNotification); err != nil {
+			return nil, fmt.Errorf("failed to enable dpg notifications, %w", err)
+		}
+
+		c.enabled = true
+	}
+
+	// Drop any response that arrived after a previous command timed out, so it
+	// is not mistaken for the response of this command.
+	select {
+	case <-c.responses:
+	default:
+	}
+
+	log.Debugf("dpg request: % x", request)
+
+	if _, err := c.characteristic.WriteWithoutResponse(request); err != nil {
+		return nil, fmt.Errorf("failed to write dpg command, %w", err)
+	}
+
+	select {
+	case response := <-c.responses:
+		log.Debugf("dpg response: % x", response)
+		return parseResponse(response)
+	case <-time.After(c.timeout):
+		return nil, ErrTimeout
+	}
+}
+
+func (c *Client) handleNotification(buf []byte) {
+	response := make([]byte, len(buf))
+	copy(response, buf)
+
+	select {
+	case c.responses <- response:
+	default:
+		log.Debugf("dropping unexpected dpg response: % x", response)
+	}
+}
+
+func parseResponse(response []byte) ([]byte, error) {
+	if len(response) < 2 {
+		return nil, ErrShortResponse
+	}
+
+	if response[0] != headerResponse {
+		return nil, fmt.Errorf("%w: response header 0x%02x", ErrCommandFailed, response[0])
+	}
+
+	length := int(response[1])
+	payload := response[2:]
+
+	if length < len(payload) {
+		payload = payload[:length]
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,119 @@
+package dpg
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeCharacteristic answers every request written to it with the next
+// response, as the desk does through a notification.
+type fakeCharacteristic struct {
+	responses [][]byte
+	requests  [][]byte
+	notify    func(buf []byte)
+}
+
+func (f *fakeCharacteristic) WriteWithoutResponse(p []byte) (int, error) {
+	f.requests = append(f.requests, append([]byte{}, p...))
+
+	if len(f.responses) > 0 {
+		response := f.responses[0]
+		f.responses = f.responses[1:]
+		f.notify(response)
+	}
+
+	return len(p), nil
+}
+
+func (f *fakeCharacteristic) EnableNotifications(callback func(buf []byte)) error {
+	f.notify = callback
+	return nil
+}
+
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response []byte
+		payload  []byte
+		err      error
+	}{
+		{"desk offset", []byte{0x01, 0x03, 0x01, 0x8c, 0x18}, []byte{0x01, 0x8c, 0x18}, nil},
+		{"trailing padding", []byte{0x01, 0x01, 0x0b, 0x00, 0x00}, []byte{0x0b}, nil},
+		{"length beyond payload", []byte{0x01, 0x04, 0x01, 0x02}, []byte{0x01, 0x02}, nil},
+		{"empty payload", []byte{0x01, 0x00}, []byte{}, nil},
+		{"rejected", []byte{0x04, 0x00}, nil, ErrCommandFailed},
+		{"short", []byte{0x01}, nil, ErrShortResponse},
+		{"empty", nil, nil, ErrShortResponse},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload, err := parseResponse(test.response)
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+
+			if !bytes.Equal(payload, test.payload) {
+				t.Errorf("expected payload % x, got % x", test.payload, payload)
+			}
+		})
+	}
+}
+
+func TestClientRead(t *testing.T) {
+	characteristic := &fakeCharacteristic{responses: [][]byte{{0x01, 0x03, 0x01, 0x8c, 0x18}}}
+	client := New(characteristic)
+
+	offset, ok, err := client.DeskOffset()
+	if err != nil || !ok {
+		t.Fatalf("expected an offset, got %v (%v)", ok, err)
+	}
+
+	if offset != 0x188c {
+		t.Errorf("expected offset 0x188c, got 0x%04x", uint16(offset))
+	}
+
+	if expected := []byte{0x7f, 0x81, 0x00}; !bytes.Equal(characteristic.requests[0], expected) {
+		t.Errorf("expected request % x, got % x", expected, characteristic.requests[0])
+	}
+}
+
+func TestClientWrite(t *testing.T) {
+	characteristic := &fakeCharacteristic{responses: [][]byte{{0x01, 0x00}}}
+	client := New(characteristic)
+
+	if err := client.SetMemoryPosition(2, 0x1234); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{0x7f, 0x8a, 0x80, 0x03, 0x01, 0x34, 0x12}
+	if !bytes.Equal(characteristic.requests[0], expected) {
+		t.Errorf("expected request % x, got % x", expected, characteristic.requests[0])
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	client := New(&fakeCharacteristic{})
+	client.timeout = 10 * time.Millisecond
+
+	if _, err := client.Read(CommandCapabilities); !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected %v, got %v", ErrTimeout, err)
+	}
+}
+
+func TestMemoryCommand(t *testing.T) {
+	for slot, expected := range map[int]Command{1: CommandMemoryPosition1, 4: CommandMemoryPosition4} {
+		if command, err := memoryCommand(slot); err != nil || command != expected {
+			t.Errorf("expected slot %d to be command 0x%02x, got 0x%02x (%v)", slot, expected, command, err)
+		}
+	}
+
+	for _, slot := range []int{0, MaxMemorySlots + 1} {
+		if _, err := memoryCommand(slot); err == nil {
+			t.Errorf("expected slot %d to be rejected", slot)
+		}
+	}
+}
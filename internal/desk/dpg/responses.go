@@ -0,0 +1,104 @@
+package dpg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Position is a desk position as reported by the controller, in tenths of a
+// millimetre.
+type Position uint16
+
+// Meters returns the position in meters.
+func (p Position) Meters() float64 {
+	return float64(p) / 10000.0
+}
+
+// PositionFromMeters converts meters into a controller position.
+func PositionFromMeters(meters float64) Position {
+	return Position(meters*10000.0 + 0.5)
+}
+
+// Capabilities describes the features supported by the desk controller.
+type Capabilities struct {
+	// MemorySize is the number of memory positions the controller stores.
+	MemorySize int
+	AutoUp     bool
+	AutoDown   bool
+	BLEAllow   bool
+	HasDisplay bool
+	HasLight   bool
+}
+
+// String returns a human-readable list of the supported features.
+func (c Capabilities) String() string {
+	var features []string
+
+	for _, feature := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"auto-up", c.AutoUp},
+		{"auto-down", c.AutoDown},
+		{"ble-allow", c.BLEAllow},
+		{"display", c.HasDisplay},
+		{"light", c.HasLight},
+	} {
+		if feature.enabled {
+			features = append(features, feature.name)
+		}
+	}
+
+	if len(features) == 0 {
+		return "none"
+	}
+
+	return strings.Join(features, ", ")
+}
+
+// ProductInfo is the raw product information reported by the desk.
+type ProductInfo []byte
+
+// String returns the product information as hex.
+func (p ProductInfo) String() string {
+	return fmt.Sprintf("% x", []byte(p))
+}
+
+// ParseCapabilities parses the payload of a CommandCapabilities response.
+func ParseCapabilities(payload []byte) (Capabilities, error) {
+	if len(payload) < 1 {
+		return Capabilities{}, fmt.Errorf("%w: capabilities", ErrShortResponse)
+	}
+
+	flags := payload[0]
+
+	return Capabilities{
+		MemorySize: int(flags & 0x07),
+		AutoUp:     flags&0x08 != 0,
+		AutoDown:   flags&0x10 != 0,
+		BLEAllow:   flags&0x20 != 0,
+		HasDisplay: flags&0x40 != 0,
+		HasLight:   flags&0x80 != 0,
+	}, nil
+}
+
+// ParsePosition parses the payload of a CommandDeskOffset or memory position
+// response. The returned bool is false when the desk reports the position as
+// not set.
+func ParsePosition(payload []byte) (Position, bool, error) {
+	if len(payload) < 3 {
+		return 0, false, fmt.Errorf("%w: position", ErrShortResponse)
+	}
+
+	if payload[0] != 0x01 {
+		return 0, false, nil
+	}
+
+	return Position(uint16(payload[2])<<8 | uint16(payload[1])), true, nil
+}
+
+// EncodePosition encodes a position to be written for a CommandDeskOffset or
+// memory position command.
+func EncodePosition(position Position) []byte {
+	return []byte{0x01, uint8(position & 0xFF), uint8(position >> 8)}
+}
@@ -0,0 +1,89 @@
+package dpg
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  []byte
+		position Position
+		ok       bool
+		err      error
+	}{
+		{"desk offset", []byte{0x01, 0x8c, 0x18}, 6284, true, nil},
+		{"memory position", []byte{0x01, 0x10, 0x27}, 10000, true, nil},
+		{"not set", []byte{0x00, 0x00, 0x00}, 0, false, nil},
+		{"not set with stale value", []byte{0x00, 0x8c, 0x18}, 0, false, nil},
+		{"short", []byte{0x01, 0x8c}, 0, false, ErrShortResponse},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			position, ok, err := ParsePosition(test.payload)
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+
+			if position != test.position || ok != test.ok {
+				t.Errorf("expected %d (%v), got %d (%v)", test.position, test.ok, position, ok)
+			}
+		})
+	}
+}
+
+func TestEncodePosition(t *testing.T) {
+	encoded := EncodePosition(6284)
+
+	if expected := []byte{0x01, 0x8c, 0x18}; !bytes.Equal(encoded, expected) {
+		t.Fatalf("expected % x, got % x", expected, encoded)
+	}
+
+	if position, ok, err := ParsePosition(encoded); err != nil || !ok || position != 6284 {
+		t.Errorf("expected the encoded position to parse back, got %d (%v, %v)", position, ok, err)
+	}
+}
+
+func TestPositionMeters(t *testing.T) {
+	if meters := Position(6284).Meters(); meters != 0.6284 {
+		t.Errorf("expected 0.6284, got %f", meters)
+	}
+
+	if position := PositionFromMeters(0.6284); position != 6284 {
+		t.Errorf("expected 6284, got %d", position)
+	}
+}
+
+func TestParseCapabilities(t *testing.T) {
+	tests := []struct {
+		name         string
+		payload      []byte
+		capabilities Capabilities
+		err          error
+	}{
+		{"idasen", []byte{0x0b, 0x00}, Capabilities{MemorySize: 3, AutoUp: true}, nil},
+		{"everything", []byte{0xfc}, Capabilities{
+			MemorySize: 4, AutoUp: true, AutoDown: true, BLEAllow: true, HasDisplay: true, HasLight: true,
+		}, nil},
+		{"nothing", []byte{0x00}, Capabilities{}, nil},
+		{"short", nil, Capabilities{}, ErrShortResponse},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			capabilities, err := ParseCapabilities(test.payload)
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+
+			if capabilities != test.capabilities {
+				t.Errorf("expected %+v, got %+v", test.capabilities, capabilities)
+			}
+		})
+	}
+}
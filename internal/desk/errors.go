@@ -1,8 +1,54 @@
 package desk
 
-var deskMoveSafetyKickIn = &deskError{msg: "desk move safety kicked in."}
+import (
+	"fmt"
+
+	"tinygo.org/x/bluetooth"
+)
+
 var bluetoothError = &deskError{msg: "bluetooth error"}
 
+// ErrOutOfRange is returned when a requested height is outside the limits of
+// the desk. The returned error is a *RangeError with the details.
+var ErrOutOfRange = &deskError{msg: "height out of range"}
+
+// ErrNotConfigured is returned when connecting without the address of the desk.
+var ErrNotConfigured = &deskError{msg: "desk is not configured, run configure first"}
+
+// ErrConnectFailed is returned when the desk could not be connected to. The
+// returned error is a *ConnectError wrapping the cause.
+var ErrConnectFailed = &deskError{msg: "failed to connect to desk"}
+
+// ErrCharacteristicMissing is returned when the desk does not have a
+// characteristic required by the request. The returned error is a
+// *CharacteristicError with the missing characteristic.
+var ErrCharacteristicMissing = &deskError{msg: "desk does not have required characteristic"}
+
+// ErrSafetyStop is returned when the desk safety feature stopped a move, e.g.
+// after colliding with something, and the safety policy gave up.
+var ErrSafetyStop = &deskError{msg: "desk move safety kicked in."}
+
+// ErrManualOverride is returned when the desk is moved by its physical buttons
+// during an automated move, the user took control of the desk.
+var ErrManualOverride = &deskError{msg: "desk moved manually during an automated move"}
+
+// ErrTimeout is returned when the desk did not reach the requested state in
+// time.
+var ErrTimeout = &deskError{msg: "timed out waiting for desk"}
+
+// ErrLocked is returned when the desk is locked against movement. The
+// returned error is a *LockedError with the reason.
+var ErrLocked = &deskError{msg: "desk is locked"}
+
+// ErrMoveStopped is returned when a move is cancelled by StopMove.
+var ErrMoveStopped = &deskError{msg: "desk move stopped"}
+
+// ErrMovePreempted is returned when a move is replaced by a move to a new
+// target before completing.
+var ErrMovePreempted = &deskError{msg: "desk move preempted by a new target"}
+
+var referenceInputUnsupported = &deskError{msg: "desk does not support reference input moves"}
+
 // circuitError is used for internally generated errors
 type deskError struct {
 	msg string
@@ -11,3 +57,70 @@ type deskError struct {
 func (m *deskError) Error() string {
 	return m.msg
 }
+
+// RangeError is returned when a height is outside the limits of the desk.
+type RangeError struct {
+	Name   string
+	Height float64
+	Min    float64
+	Max    float64
+}
+
+func (e *RangeError) Error() string {
+	if e.Height > e.Max {
+		return fmt.Sprintf("provided %s (%.2f) exceeds maximum height (%.2f)", e.Name, e.Height, e.Max)
+	}
+
+	return fmt.Sprintf("provided %s (%.2f) is below minimum height (%.2f)", e.Name, e.Height, e.Min)
+}
+
+func (e *RangeError) Is(target error) bool {
+	return target == ErrOutOfRange
+}
+
+// ConnectError is returned when the desk at the address could not be connected
+// to.
+type ConnectError struct {
+	Address string
+	Err     error
+}
+
+func (e *ConnectError) Error() string {
+	return fmt.Sprintf("%s %s, %s", ErrConnectFailed.Error(), e.Address, e.Err)
+}
+
+func (e *ConnectError) Is(target error) bool {
+	return target == ErrConnectFailed
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// CharacteristicError is returned when the desk does not have a characteristic
+// required by the request.
+type CharacteristicError struct {
+	UUID bluetooth.UUID
+}
+
+func (e *CharacteristicError) Error() string {
+	return fmt.Sprintf("does not have required characteristic: %s", e.UUID.String())
+}
+
+func (e *CharacteristicError) Is(target error) bool {
+	return target == ErrCharacteristicMissing
+}
+
+// LockedError is returned when the desk is locked against movement, e.g. by
+// the lock command or during quiet hours.
+type LockedError struct {
+	Reason string
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrLocked.Error(), e.Reason)
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
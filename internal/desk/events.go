@@ -0,0 +1,67 @@
+package desk
+
+import (
+	"time"
+)
+
+// EventType identifies what happened to the desk.
+type EventType string
+
+const (
+	// EventMoveStarted is emitted when an automated move starts.
+	EventMoveStarted EventType = "move_started"
+
+	// EventTargetReached is emitted when an automated move reaches its target.
+	EventTargetReached EventType = "target_reached"
+
+	// EventManualOverride is emitted when the desk is moved by its physical
+	// buttons during an automated move, aborting the move.
+	EventManualOverride EventType = "manual_override"
+
+	// EventSafetyKickIn is emitted when the desk safety feature stops an
+	// automated move, with the height and direction the desk was moving in.
+	EventSafetyKickIn EventType = "safety_kick_in"
+
+	// EventMotorStopped is emitted when the desk stops after being moved, with
+	// how long the motor was driven for, however the move ended.
+	EventMotorStopped EventType = "motor_stopped"
+
+	// EventManualMove is emitted while monitoring once the desk stops after
+	// being moved with its physical buttons, with the direction it moved in.
+	EventManualMove EventType = "manual_move"
+
+	// EventConnected is emitted when the desk is connected to, including
+	// reconnecting after the connection was released.
+	EventConnected EventType = "connected"
+
+	// EventDisconnected is emitted when the desk is disconnected from.
+	EventDisconnected EventType = "disconnected"
+)
+
+// Event describes something that happened to the desk, passed to the handlers
+// registered through WithEventHandler.
+type Event struct {
+	Type      EventType     `json:"type"`
+	Time      time.Time     `json:"time"`
+	Height    float64       `json:"height"`
+	Target    float64       `json:"target,omitempty"`
+	Direction string        `json:"direction,omitempty"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Profile   string        `json:"profile,omitempty"`
+}
+
+// emit passes the event to all registered event handlers.
+func (d *Desk) emit(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	if event.Profile == "" {
+		event.Profile = d.profile
+	}
+
+	for _, handler := range d.eventHandlers {
+		handler(event)
+	}
+}
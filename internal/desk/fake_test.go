@@ -0,0 +1,267 @@
+package desk
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"tinygo.org/x/bluetooth"
+)
+
+const (
+	// fakeStep is how far the fake desk moves for every up or down command,
+	// in tenths of a millimetre.
+	fakeStep = 50
+
+	// fakeSafetyReverse is how far the safety feature of the fake desk
+	// reverses it after hitting an obstacle, in tenths of a millimetre.
+	fakeSafetyReverse = 200
+)
+
+// fakeWrite is a single write to a characteristic of the fake desk.
+type fakeWrite struct {
+	uuid bluetooth.UUID
+	data []byte
+}
+
+// fakeDesk simulates a desk behind its characteristics, moving a step for
+// every up or down command and notifying the new height.
+type fakeDesk struct {
+	mu     sync.Mutex
+	raw    int
+	reads  int
+	writes []fakeWrite
+	notify func(buf []byte)
+
+	// obstacle is the height the desk hits when moving up, as many times as
+	// obstacleHits, reversing it a little like the safety feature does.
+	obstacle     int
+	obstacleHits int
+
+	// ignoreReference makes the desk ignore the reference input, like
+	// firmware without support for it.
+	ignoreReference bool
+
+	// firmware is the firmware revision the desk reports.
+	firmware string
+
+	// connected is whether a connection is open to the desk, dials how often
+	// it was connected to.
+	connected bool
+	dials     int
+}
+
+// newFakeDesk creates a fake desk at the given height in meters.
+func newFakeDesk(height float64) *fakeDesk {
+	return &fakeDesk{raw: int((height-MinHeight)*10000 + 0.5)}
+}
+
+// newTestDesk creates a desk communicating with the fake desk.
+func newTestDesk(f *fakeDesk, opts ...Option) *Desk {
+	characteristics := WithCharacteristics(
+		&fakeCharacteristic{uuid: UuidHeight, desk: f},
+		&fakeCharacteristic{uuid: UuidCommand, desk: f},
+		&fakeCharacteristic{uuid: UuidReferenceInput, desk: f},
+		&fakeCharacteristic{uuid: bluetooth.CharacteristicUUIDFirmwareRevisionString, desk: f},
+	)
+
+	d, _ := NewDesk("", "", false, append([]Option{characteristics}, opts...)...)
+	return d
+}
+
+// fakeConnection is a connection to the fake desk, dropping its height
+// notifications once disconnected.
+type fakeConnection struct {
+	desk *fakeDesk
+}
+
+func (c *fakeConnection) Disconnect() error {
+	c.desk.mu.Lock()
+	defer c.desk.mu.Unlock()
+
+	c.desk.notify = nil
+	c.desk.connected = false
+	return nil
+}
+
+// newConnectedTestDesk creates a desk connected to the fake desk, which can
+// be disconnected from and reconnected to.
+func newConnectedTestDesk(t *testing.T, f *fakeDesk, opts ...Option) *Desk {
+	t.Helper()
+
+	d, _ := NewDesk("", "AA:BB:CC:DD:EE:FF", false, opts...)
+	d.dial = func() (connection, []Characteristic, error) {
+		f.mu.Lock()
+		f.connected = true
+		f.dials++
+		f.mu.Unlock()
+
+		return &fakeConnection{desk: f}, []Characteristic{
+			&fakeCharacteristic{uuid: UuidHeight, desk: f},
+			&fakeCharacteristic{uuid: UuidCommand, desk: f},
+			&fakeCharacteristic{uuid: UuidReferenceInput, desk: f},
+		}, nil
+	}
+
+	d.connMu.Lock()
+	err := d.connectLocked()
+	d.connMu.Unlock()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func (f *fakeDesk) heightBytes() []byte {
+	return []byte{byte(f.raw), byte(f.raw >> 8), 0, 0}
+}
+
+// placeObstacle makes the desk hit an obstacle at the height in meters when
+// moving up, the given number of times.
+func (f *fakeDesk) placeObstacle(height float64, hits int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.obstacle = int((height-MinHeight)*10000 + 0.5)
+	f.obstacleHits = hits
+}
+
+// height returns the height of the fake desk in meters.
+func (f *fakeDesk) height() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return float64(f.raw)/10000 + MinHeight
+}
+
+// writesTo returns the data written to the characteristic, in order.
+func (f *fakeDesk) writesTo(uuid bluetooth.UUID) [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var writes [][]byte
+	for _, write := range f.writes {
+		if write.uuid == uuid {
+			writes = append(writes, write.data)
+		}
+	}
+
+	return writes
+}
+
+// readCount returns how often the height has been read directly.
+func (f *fakeDesk) readCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reads
+}
+
+// command applies a command written to the desk, returning the height to
+// notify or nil if the desk did not move.
+func (f *fakeDesk) command(p []byte) []byte {
+	switch p[0] {
+	case 0x47:
+		if f.obstacleHits > 0 && f.raw+fakeStep > f.obstacle {
+			f.obstacleHits--
+			f.raw -= fakeSafetyReverse
+			break
+		}
+
+		f.raw += fakeStep
+	case 0x46:
+		f.raw -= fakeStep
+	default:
+		return nil
+	}
+
+	return f.heightBytes()
+}
+
+// reference applies a reference input written to the desk, moving a step
+// towards the target, returning the height to notify or nil if the desk did
+// not move.
+func (f *fakeDesk) reference(p []byte) []byte {
+	target := int(p[0]) | int(p[1])<<8
+
+	switch {
+	case f.ignoreReference || target == 0x8001:
+		// 0x8001 clears the reference input when stopping.
+		return nil
+	case target > f.raw:
+		f.raw = min(target, f.raw+fakeStep)
+	case target < f.raw:
+		f.raw = max(target, f.raw-fakeStep)
+	default:
+		return nil
+	}
+
+	return f.heightBytes()
+}
+
+type fakeCharacteristic struct {
+	uuid bluetooth.UUID
+	desk *fakeDesk
+}
+
+func (c *fakeCharacteristic) UUID() bluetooth.UUID {
+	return c.uuid
+}
+
+func (c *fakeCharacteristic) Read(data []byte) (int, error) {
+	c.desk.mu.Lock()
+	defer c.desk.mu.Unlock()
+
+	if c.uuid == bluetooth.CharacteristicUUIDFirmwareRevisionString {
+		return copy(data, c.desk.firmware), nil
+	}
+
+	c.desk.reads++
+	return copy(data, c.desk.heightBytes()), nil
+}
+
+func (c *fakeCharacteristic) WriteWithoutResponse(p []byte) (int, error) {
+	c.desk.mu.Lock()
+	c.desk.writes = append(c.desk.writes, fakeWrite{uuid: c.uuid, data: append([]byte{}, p...)})
+
+	var notification []byte
+	switch c.uuid {
+	case UuidCommand:
+		notification = c.desk.command(p)
+	case UuidReferenceInput:
+		notification = c.desk.reference(p)
+	}
+
+	notify := c.desk.notify
+	c.desk.mu.Unlock()
+
+	// The desk takes a moment to act on every command.
+	time.Sleep(time.Millisecond)
+
+	if notification != nil && notify != nil {
+		notify(notification)
+	}
+
+	return len(p), nil
+}
+
+func (c *fakeCharacteristic) EnableNotifications(callback func(buf []byte)) error {
+	c.desk.mu.Lock()
+	defer c.desk.mu.Unlock()
+
+	if c.uuid == UuidHeight {
+		c.desk.notify = callback
+	}
+
+	return nil
+}
+
+// assertHeight fails the test if the desk is not within 5mm of the height.
+func assertHeight(t *testing.T, f *fakeDesk, expected float64) {
+	t.Helper()
+
+	if height := f.height(); height < expected-0.005 || height > expected+0.005 {
+		t.Errorf("expected desk at %.3f, got %.3f", expected, height)
+	}
+}
@@ -0,0 +1,44 @@
+package desk
+
+import (
+	"math"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// checkGuard checks the guards registered through WithGuard and WithMoveGuard
+// before the action moves the desk the distance, logging the source of any
+// refused movement, e.g. the routed process the action was requested by.
+func (d *Desk) checkGuard(source, action string, distance float64) error {
+	for _, guard := range d.guards {
+		if err := guard(); err != nil {
+			log.WithField("source", source).WithError(err).Warnf("refused to %s", action)
+			return err
+		}
+	}
+
+	for _, guard := range d.moveGuards {
+		if err := guard(distance); err != nil {
+			log.WithField("source", source).WithError(err).Warnf("refused to %s", action)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// distanceTo returns how far the desk is from the target for the move guards,
+// zero when there are none or the height can't be read.
+func (d *Desk) distanceTo(target float64) float64 {
+	if len(d.moveGuards) == 0 {
+		return 0
+	}
+
+	height, err := d.GetHeight()
+	if err != nil {
+		log.WithError(err).Debug("failed to read the height before moving")
+		return 0
+	}
+
+	return math.Abs(target - height)
+}
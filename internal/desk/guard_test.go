@@ -0,0 +1,135 @@
+package desk
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMoveGuardDistance(t *testing.T) {
+	f := newFakeDesk(0.75)
+	refused := errors.New("refused")
+
+	var distances []float64
+	d := newTestDesk(f, WithMoveGuard(func(distance float64) error {
+		distances = append(distances, distance)
+		return refused
+	}))
+
+	if err := d.MoveToTarget(1.05); !errors.Is(err, refused) {
+		t.Fatalf("expected the move to be refused, got %v", err)
+	}
+
+	if err := d.MoveToTarget(0.70); !errors.Is(err, refused) {
+		t.Fatalf("expected the move to be refused, got %v", err)
+	}
+
+	if err := d.MoveDirection(UP); !errors.Is(err, refused) {
+		t.Fatalf("expected the move to be refused, got %v", err)
+	}
+
+	expected := []float64{0.30, 0.05, 0}
+	if len(distances) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, distances)
+	}
+
+	for i := range expected {
+		if math.Abs(distances[i]-expected[i]) > 0.001 {
+			t.Errorf("expected %v, got %v", expected, distances)
+		}
+	}
+
+	assertHeight(t, f, 0.75)
+
+	if writes := f.writesTo(UuidCommand); len(writes) != 0 {
+		t.Errorf("expected a refused move not to move the desk, got %d commands", len(writes))
+	}
+}
+
+func TestGuardBeforeMoveGuard(t *testing.T) {
+	locked := &LockedError{Reason: "locked"}
+	checked := false
+
+	d := newTestDesk(newFakeDesk(0.75),
+		WithMoveGuard(func(float64) error {
+			checked = true
+			return nil
+		}),
+		WithGuard(func() error { return locked }),
+	)
+
+	if err := d.MoveToTarget(1.0); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected the move to be locked, got %v", err)
+	}
+
+	if checked {
+		t.Error("expected the move guard not to be checked once refused")
+	}
+}
+
+// TestMoveToTargetFor expects a move for a requester to be limited by its soft
+// limits instead of the desk's, and its events to be attributed to its profile.
+func TestMoveToTargetFor(t *testing.T) {
+	f := newFakeDesk(0.75)
+	events := &eventRecorder{}
+	d := newTestDesk(f, WithEventHandler(events.record), WithSoftLimits(0.70, 1.00), WithProfile("alice"))
+
+	bob := Requester{Source: "test", Profile: "bob", MinHeight: 0.80, MaxHeight: 1.10}
+
+	if err := d.MoveToTargetFor(bob, 0.75); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("expected the requester's minimum to apply, got %v", err)
+	}
+
+	if err := d.MoveToTargetFor(bob, 1.05); err != nil {
+		t.Fatalf("expected the requester's maximum to apply, got %v", err)
+	}
+
+	assertHeight(t, f, 1.05)
+
+	events.mu.Lock()
+	defer events.mu.Unlock()
+
+	if len(events.events) == 0 {
+		t.Fatal("expected the move to emit events")
+	}
+
+	for _, event := range events.events {
+		if event.Profile != "bob" {
+			t.Errorf("expected %s to be attributed to bob, got %q", event.Type, event.Profile)
+		}
+	}
+}
+
+func TestJogGuardDistance(t *testing.T) {
+	f := newFakeDesk(0.75)
+	refused := errors.New("refused")
+
+	var distances []float64
+	d := newTestDesk(f, WithMoveGuard(func(distance float64) error {
+		distances = append(distances, distance)
+		return refused
+	}))
+
+	for _, jog := range []struct {
+		direction Direction
+		duration  time.Duration
+	}{{UP, 2 * time.Second}, {DOWN, 10 * time.Second}, {UP, 0}} {
+		if err := d.Jog(jog.direction, jog.duration, nil); !errors.Is(err, refused) {
+			t.Fatalf("expected the jog to be refused, got %v", err)
+		}
+	}
+
+	// Two seconds at the nominal speed, down to the minimum height and the
+	// unknown distance of a held jog.
+	expected := []float64{2 * NominalSpeed, 0.75 - MinHeight, 0}
+	if len(distances) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, distances)
+	}
+
+	for i := range expected {
+		if math.Abs(distances[i]-expected[i]) > 0.001 {
+			t.Errorf("expected %v, got %v", expected, distances)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package desk
+
+import (
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"tinygo.org/x/bluetooth"
+)
+
+// DeskInfo is the information the desk reports about itself through the
+// standard Device Information Service and the GAP device name.
+type DeskInfo struct {
+	DeviceName       string `json:"device_name"`
+	Manufacturer     string `json:"manufacturer"`
+	ModelNumber      string `json:"model_number"`
+	SerialNumber     string `json:"serial_number"`
+	FirmwareRevision string `json:"firmware_revision"`
+	HardwareRevision string `json:"hardware_revision"`
+	SoftwareRevision string `json:"software_revision"`
+}
+
+// FirmwareAtLeast reports if the firmware revision is equal to or newer than
+// the given dotted version, e.g. "1.2". Non-numeric parts of the revision are
+// ignored. Returns false if the desk did not report a firmware revision.
+func (i DeskInfo) FirmwareAtLeast(version string) bool {
+	if i.FirmwareRevision == "" {
+		return false
+	}
+
+	current := parseVersion(i.FirmwareRevision)
+	required := parseVersion(version)
+
+	for index, value := range required {
+		var have int
+		if index < len(current) {
+			have = current[index]
+		}
+
+		if have != value {
+			return have > value
+		}
+	}
+
+	return true
+}
+
+// Info reads the device information reported by the desk. Values not reported
+// by the desk are left empty.
+func (d *Desk) Info() DeskInfo {
+	return DeskInfo{
+		DeviceName:       d.readString(bluetooth.CharacteristicUUIDDeviceName),
+		Manufacturer:     d.readString(bluetooth.CharacteristicUUIDManufacturerNameString),
+		ModelNumber:      d.readString(bluetooth.CharacteristicUUIDModelNumberString),
+		SerialNumber:     d.readString(bluetooth.CharacteristicUUIDSerialNumberString),
+		FirmwareRevision: d.readString(bluetooth.CharacteristicUUIDFirmwareRevisionString),
+		HardwareRevision: d.readString(bluetooth.CharacteristicUUIDHardwareRevisionString),
+		SoftwareRevision: d.readString(bluetooth.CharacteristicUUIDSoftwareRevisionString),
+	}
+}
+
+// readString reads the characteristic as a string, returning an empty string
+// if the characteristic does not exist or cannot be read.
+func (d *Desk) readString(uuid bluetooth.UUID) string {
+	characteristic := d.getCharacteristic(uuid)
+
+	if characteristic == nil {
+		return ""
+	}
+
+	data := make([]byte, 128)
+	n, err := characteristic.Read(data)
+
+	if err != nil {
+		log.WithError(err).Debugf("failed to read characteristic %s", uuid.String())
+		return ""
+	}
+
+	return strings.TrimSpace(strings.TrimRight(string(data[:min(n, len(data))]), "\x00"))
+}
+
+// parseVersion parses the numeric parts of a dotted version, e.g. "V1.2.3"
+// results in [1, 2, 3].
+func parseVersion(version string) []int {
+	var parts []int
+
+	for _, part := range strings.Split(version, ".") {
+		digits := strings.TrimFunc(part, func(r rune) bool {
+			return r < '0' || r > '9'
+		})
+
+		value, _ := strconv.Atoi(digits)
+		parts = append(parts, value)
+	}
+
+	return parts
+}
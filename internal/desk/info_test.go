@@ -0,0 +1,30 @@
+package desk
+
+import "testing"
+
+func TestFirmwareAtLeast(t *testing.T) {
+	tests := []struct {
+		firmware string
+		version  string
+		expected bool
+	}{
+		{"1.2", "1.2", true},
+		{"1.3", "1.2", true},
+		{"2.0", "1.9", true},
+		{"1.10", "1.9", true},
+		{"1.1", "1.2", false},
+		{"0.9.9", "1.0", false},
+		{"V1.2.1", "1.2", true},
+		{"1.2", "1.2.1", false},
+		{"1.2.0", "1.2", true},
+		{"", "0", false},
+	}
+
+	for _, test := range tests {
+		info := DeskInfo{FirmwareRevision: test.firmware}
+
+		if at := info.FirmwareAtLeast(test.version); at != test.expected {
+			t.Errorf("expected %q at least %q to be %v, got %v", test.firmware, test.version, test.expected, at)
+		}
+	}
+}
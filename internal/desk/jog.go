@@ -0,0 +1,70 @@
+package desk
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Jog moves the desk in the direction for the duration, or until done is closed
+// when the duration is zero. The desk stops early at its move limits.
+//
+// Jogging is a move towards the limit in the direction, cancelled once the
+// time is up, so it goes through the same safety checks and safety policy and
+// is preempted by other moves like any other move.
+func (d *Desk) Jog(direction Direction, duration time.Duration, done <-chan struct{}) error {
+	minHeight, maxHeight := d.MoveLimits()
+
+	limit := maxHeight
+	if direction == DOWN {
+		limit = minHeight
+	} else if direction != UP {
+		return fmt.Errorf("unknown jog direction %s", direction)
+	}
+
+	// The desk moves about the nominal speed for the duration, but no further
+	// than the limit. How far a held jog goes is unknown.
+	distance := 0.0
+	if duration > 0 {
+		distance = duration.Seconds() * NominalSpeed
+
+		if toLimit := d.distanceTo(limit); toLimit > 0 {
+			distance = math.Min(distance, toLimit)
+		}
+	}
+
+	if err := d.checkGuard(d.source, fmt.Sprintf("jog %s", direction), distance); err != nil {
+		return err
+	}
+
+	log.Infof("jogging desk %s", direction)
+
+	request := d.startMove(limit, d.profile)
+
+	var elapsed <-chan time.Time
+	if duration > 0 {
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		elapsed = timer.C
+	}
+
+	select {
+	case err := <-request.done:
+		return err
+	case <-elapsed:
+	case <-done:
+	}
+
+	if !d.cancelMove(request, nil) {
+		// The move completed or was preempted while being cancelled.
+		return <-request.done
+	}
+
+	// Wait for the move loop to stop the desk before returning, the caller
+	// may disconnect straight away.
+	<-request.done
+	<-d.movesIdle()
+	return nil
+}
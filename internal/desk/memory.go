@@ -0,0 +1,68 @@
+package desk
+
+import (
+	"fmt"
+
+	"idasen-desk/internal/desk/dpg"
+)
+
+// MemorySlots returns the number of memory positions the desk stores.
+func (d *Desk) MemorySlots() (int, error) {
+	client, err := d.DPG()
+	if err != nil {
+		return 0, err
+	}
+
+	capabilities, err := client.Capabilities()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read desk capabilities, %w", err)
+	}
+
+	return min(capabilities.MemorySize, dpg.MaxMemorySlots), nil
+}
+
+// MemoryPosition returns the height stored within the desks own memory slot,
+// starting at 1. The returned bool is false if the slot is empty.
+func (d *Desk) MemoryPosition(slot int) (float64, bool, error) {
+	client, err := d.DPG()
+	if err != nil {
+		return 0, false, err
+	}
+
+	position, ok, err := client.MemoryPosition(slot)
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+
+	return position.Meters() + d.minHeight, true, nil
+}
+
+// SetMemoryPosition stores the given height within the desks own memory slot,
+// starting at 1. Within the constraints of the device min value and max value.
+func (d *Desk) SetMemoryPosition(slot int, height float64) error {
+	if err := d.validateHeight("height", height); err != nil {
+		return err
+	}
+
+	client, err := d.DPG()
+	if err != nil {
+		return err
+	}
+
+	return client.SetMemoryPosition(slot, dpg.PositionFromMeters(height-d.minHeight))
+}
+
+// MoveToMemoryPosition moves the desk to the height stored within the desks
+// own memory slot, starting at 1.
+func (d *Desk) MoveToMemoryPosition(slot int) error {
+	height, ok, err := d.MemoryPosition(slot)
+	if err != nil {
+		return fmt.Errorf("failed to read memory position %d, %w", slot, err)
+	}
+
+	if !ok {
+		return fmt.Errorf("memory position %d is not set", slot)
+	}
+
+	return d.MoveToTarget(height)
+}
@@ -0,0 +1,604 @@
+package desk
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"tinygo.org/x/bluetooth"
+)
+
+const (
+	// referenceInputInterval is how often the target is rewritten to the
+	// reference input while the desk is moving.
+	referenceInputInterval = 200 * time.Millisecond
+
+	// referenceSettleDuration is how long the height has to be unchanged before
+	// the desk is considered to have settled at the target.
+	referenceSettleDuration = 500 * time.Millisecond
+
+	// referenceStallDuration is how long the desk can go without reporting a
+	// height change before the move is considered stalled.
+	referenceStallDuration = 1500 * time.Millisecond
+
+	// preemptGracePeriod is how long safety detection is suspended after the
+	// target of an in-flight move changes, as the desk keeps moving in the old
+	// direction while it decelerates.
+	preemptGracePeriod = time.Second
+
+	// NominalSpeed is the speed the motor moves the desk at in meters per
+	// second, used to estimate how far or how long a move goes.
+	NominalSpeed = 0.038
+)
+
+// moveRequest is a single request to move the desk to a target.
+type moveRequest struct {
+	target  float64
+	started time.Time
+	done    chan error
+
+	// profile is the user profile the events of the move are attributed to.
+	profile string
+
+	// from is the height of the desk when the move towards the request
+	// started, used to return the desk after the safety feature kicked in.
+	from float64
+}
+
+// moveQueue serialises movement of the desk. A single move loop drives the
+// desk at a time, towards the target of the latest request. A new request
+// preempts the previous one by replacing its target.
+type moveQueue struct {
+	mu      sync.Mutex
+	running bool
+	active  *moveRequest
+
+	// idle is closed once the move loop ends, after stopping the desk.
+	idle chan struct{}
+
+	// driven is how long the move loop has been sending commands to the desk
+	// for, not counting the time since drivingSince while it still is.
+	driven       time.Duration
+	drivingSince time.Time
+}
+
+// MoveToTarget move the desk to the specified target float value. Within the
+// constraints of the device min value and max value.
+//
+// When configured with MoveModeReference the desk is asked to move itself to
+// the target, falling back to direction moves if the firmware ignores it.
+//
+// Safe for concurrent use. A move that is already in flight is preempted by a
+// new target, the desk changes direction (if needed) without stopping first and
+// the preempted call returns ErrMovePreempted.
+func (d *Desk) MoveToTarget(target float64) error {
+	return d.MoveToTargetFor(d.requester(), target)
+}
+
+// Requester describes who a move is made on behalf of, e.g. the user of a
+// request routed from another process.
+type Requester struct {
+	// Source describes where the request came from, logged when refused.
+	Source string
+	// Profile is the user profile the events of the move are attributed to.
+	Profile string
+	// MinHeight and MaxHeight are the soft limits of the requester, used
+	// instead of the ones set through WithSoftLimits. Zero for no limit.
+	MinHeight float64
+	MaxHeight float64
+}
+
+// requester returns the requester of moves made directly on the desk.
+func (d *Desk) requester() Requester {
+	return Requester{Source: d.source, Profile: d.profile, MinHeight: d.softMinHeight, MaxHeight: d.softMaxHeight}
+}
+
+// MoveToTargetFor moves the desk to the target the same as MoveToTarget, on
+// behalf of the given requester, within its soft limits instead of the ones of
+// the desk.
+func (d *Desk) MoveToTargetFor(requester Requester, target float64) error {
+	minHeight, maxHeight := d.moveLimits(requester.MinHeight, requester.MaxHeight)
+	if target > maxHeight || target < minHeight {
+		return &RangeError{Name: "target", Height: target, Min: minHeight, Max: maxHeight}
+	}
+
+	if err := d.checkGuard(requester.Source, fmt.Sprintf("move to %.2f", target), d.distanceTo(target)); err != nil {
+		return err
+	}
+
+	return <-d.startMove(target, requester.Profile).done
+}
+
+// startMove queues a move to the target for the profile, preempting any move
+// in flight, and returns the request which completes once the move has.
+func (d *Desk) startMove(target float64, profile string) *moveRequest {
+	request := &moveRequest{target: target, started: time.Now(), done: make(chan error, 1), profile: profile}
+
+	d.moves.mu.Lock()
+	previous, running := d.moves.active, d.moves.running
+	d.moves.active = request
+	d.moves.running = true
+
+	if !running {
+		d.moves.idle = make(chan struct{})
+	}
+
+	d.moves.mu.Unlock()
+
+	if !running {
+		go d.runMoves()
+	} else if previous != nil {
+		log.Infof("preempting move to %.2f with %.2f", previous.target, target)
+		previous.done <- ErrMovePreempted
+	}
+
+	return request
+}
+
+// StopMove stops the desk, cancelling the move in flight if there is one,
+// which then returns ErrMoveStopped. Safe for concurrent use.
+func (d *Desk) StopMove() error {
+	d.moves.mu.Lock()
+	active := d.moves.active
+	d.moves.mu.Unlock()
+
+	if active != nil && d.cancelMove(active, ErrMoveStopped) {
+		<-d.movesIdle()
+	}
+
+	return d.Stop()
+}
+
+// movesIdle returns a channel closed once the move loop is no longer running,
+// after it has emitted all of its events.
+func (d *Desk) movesIdle() <-chan struct{} {
+	d.moves.mu.Lock()
+	defer d.moves.mu.Unlock()
+
+	if d.moves.idle == nil {
+		idle := make(chan struct{})
+		close(idle)
+		return idle
+	}
+
+	return d.moves.idle
+}
+
+// cancelMove cancels the request if it is still the active one, completing it
+// with the given error. The move loop stops the desk once it notices.
+func (d *Desk) cancelMove(request *moveRequest, err error) bool {
+	d.moves.mu.Lock()
+	defer d.moves.mu.Unlock()
+
+	if d.moves.active != request {
+		return false
+	}
+
+	log.Infof("cancelling move to %.2f", request.target)
+
+	d.moves.active = nil
+	request.done <- err
+	return true
+}
+
+// activeMove returns the latest move request, the one the move loop should be
+// moving towards.
+func (d *Desk) activeMove() *moveRequest {
+	d.moves.mu.Lock()
+	defer d.moves.mu.Unlock()
+	return d.moves.active
+}
+
+// runMoves drives the desk until the active move request completes. The
+// request is only completed if it is still the active one, otherwise it was
+// preempted while finishing and the loop continues with the new request, or
+// it was cancelled and the loop ends.
+func (d *Desk) runMoves() {
+	d.moves.mu.Lock()
+	d.moves.driven = 0
+	d.moves.mu.Unlock()
+
+	err := d.prepareMove()
+	if err == nil {
+		err = d.enableHeightNotifications()
+	}
+
+	for {
+		d.moves.mu.Lock()
+		request := d.moves.active
+
+		if request == nil {
+			d.moves.running = false
+			idle, driven := d.moves.idle, d.moves.driven
+			d.moves.mu.Unlock()
+
+			d.emitMotorStopped(driven, nil, nil)
+			close(idle)
+			return
+		}
+
+		d.moves.mu.Unlock()
+
+		if err == nil {
+			d.emitMoveStarted(request)
+			request, err = d.moveWithSafetyPolicy(request)
+		}
+
+		d.moves.mu.Lock()
+
+		if d.moves.active != request {
+			d.moves.mu.Unlock()
+			err = nil
+			continue
+		}
+
+		d.moves.active = nil
+		d.moves.running = false
+		idle, driven := d.moves.idle, d.moves.driven
+		d.moves.mu.Unlock()
+
+		if err == nil {
+			d.emit(Event{
+				Type:     EventTargetReached,
+				Height:   d.lastReading().height,
+				Target:   request.target,
+				Duration: time.Since(request.started),
+				Profile:  request.profile,
+			})
+		}
+
+		// Events are emitted before completing the request, the caller may
+		// exit as soon as it has.
+		d.emitMotorStopped(driven, request, err)
+		request.done <- err
+		close(idle)
+		return
+	}
+}
+
+// emitMotorStopped emits how long the motor was driven for by the move loop,
+// used to track the duty cycle of the motor. The request is the one the loop
+// completed with the error, nil if the loop ended after the move was
+// cancelled.
+func (d *Desk) emitMotorStopped(driven time.Duration, request *moveRequest, err error) {
+	event := Event{
+		Type:     EventMotorStopped,
+		Height:   d.lastReading().height,
+		Duration: driven,
+	}
+
+	if request != nil {
+		event.Target = request.target
+		event.Profile = request.profile
+	}
+
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	d.emit(event)
+}
+
+func (d *Desk) emitMoveStarted(request *moveRequest) {
+	height := d.lastReading().height
+	if request.from == 0 {
+		request.from = height
+	}
+
+	d.emit(Event{
+		Type:    EventMoveStarted,
+		Height:  height,
+		Target:  request.target,
+		Profile: request.profile,
+	})
+}
+
+// startDriving marks the move loop as sending commands to the desk, until
+// stopDriving is called.
+func (d *Desk) startDriving() {
+	d.moves.mu.Lock()
+	defer d.moves.mu.Unlock()
+
+	if d.moves.drivingSince.IsZero() {
+		d.moves.drivingSince = time.Now()
+	}
+}
+
+// stopDriving adds the time since startDriving to how long the motor was
+// driven for. Does nothing if the loop is not driving the desk.
+func (d *Desk) stopDriving() {
+	d.moves.mu.Lock()
+	defer d.moves.mu.Unlock()
+
+	if !d.moves.drivingSince.IsZero() {
+		d.moves.driven += time.Since(d.moves.drivingSince)
+		d.moves.drivingSince = time.Time{}
+	}
+}
+
+// directionTo returns the direction the desk has to move in to reach the
+// target from the given height.
+func directionTo(height, target float64) Direction {
+	if target > height {
+		return UP
+	}
+
+	return DOWN
+}
+
+// moveTo moves the desk using the configured move mode, returning the request
+// that was active when the move completed. Only the time spent in here counts
+// as the motor being driven, not waking the desk or waiting to retry.
+func (d *Desk) moveTo(request *moveRequest) (*moveRequest, error) {
+	d.startDriving()
+	defer d.stopDriving()
+
+	if d.moveMode == MoveModeReference && d.referenceFirmware() {
+		active, err := d.moveToTargetReference(request)
+		if !errors.Is(err, referenceInputUnsupported) {
+			return active, err
+		}
+
+		log.Warn("desk did not respond to reference input, falling back to direction moves")
+		request = active
+	}
+
+	return d.moveToTargetDirection(request)
+}
+
+// referenceFirmware returns true if the firmware of the desk is recent enough
+// to try the reference input, always when no minimum is configured.
+func (d *Desk) referenceFirmware() bool {
+	if d.referenceMinFirmware == "" {
+		return true
+	}
+
+	info := DeskInfo{FirmwareRevision: d.readString(bluetooth.CharacteristicUUIDFirmwareRevisionString)}
+	if info.FirmwareAtLeast(d.referenceMinFirmware) {
+		return true
+	}
+
+	log.Debugf("firmware %q is older than %s, moving by direction", info.FirmwareRevision, d.referenceMinFirmware)
+	return false
+}
+
+// moveToTargetReference writes the target height to the reference input
+// characteristic and lets the desk ramp towards it. The desk only keeps moving
+// while the reference input is refreshed, so the target is rewritten on every
+// interval until the desk settles.
+//
+// Returns referenceInputUnsupported if the desk never starts moving, allowing
+// the caller to fall back to direction moves.
+func (d *Desk) moveToTargetReference(request *moveRequest) (*moveRequest, error) {
+	referenceCharacteristic := d.getCharacteristic(UuidReferenceInput)
+
+	if referenceCharacteristic == nil {
+		return request, referenceInputUnsupported
+	}
+
+	startHeight := d.lastReading().height
+	startDifference := math.Abs(request.target - startHeight)
+	direction := directionTo(startHeight, request.target)
+	closest := startHeight
+	reference := d.metersToBytes(request.target)
+	moved := false
+	started := time.Now()
+	var graceUntil time.Time
+
+	log.Infof("moving desk from %.2f to %.2f using reference input", startHeight, request.target)
+
+	for {
+		reading := d.lastReading()
+
+		// A new target preempted the current one, continue towards the new
+		// target straight away, the desk ramps into the new direction itself.
+		if active := d.activeMove(); active != request {
+			if active == nil {
+				return request, d.Stop()
+			}
+
+			request = active
+			startDifference = math.Abs(request.target - reading.height)
+			direction = directionTo(reading.height, request.target)
+			closest = reading.height
+			reference = d.metersToBytes(request.target)
+			started = time.Now()
+			graceUntil = started.Add(preemptGracePeriod)
+
+			log.Infof("moving desk from %.2f to %.2f using reference input", reading.height, request.target)
+			d.emitMoveStarted(request)
+		}
+
+		moved = moved || math.Abs(reading.height-startHeight) > 0.001
+
+		// The desk stalls or settles once it hasn't reported a height for a
+		// while, a reading from before the move started, e.g. the end of an
+		// earlier move, says nothing about this move.
+		since := time.Since(reading.at)
+		if reading.at.Before(started) {
+			since = time.Since(started)
+		}
+		differenceAbs := math.Abs(request.target - reading.height)
+
+		if differenceAbs < math.Abs(request.target-closest) {
+			closest = reading.height
+		}
+
+		log.Debugf("target=%f, current_height=%f, speed=%f, difference=%f",
+			request.target, reading.height, reading.speed, differenceAbs)
+
+		// The desk settled within our tolerance, clear the reference input so
+		// it does not continue to hold the target.
+		if differenceAbs <= 0.005 && (reading.speed == 0 || since > referenceSettleDuration) {
+			if stopErr := d.Stop(); stopErr != nil {
+				return request, stopErr
+			}
+
+			log.Infof("reached target of %.3f, actual: %.3f", request.target, reading.height)
+			return request, nil
+		}
+
+		// Moving further away from the target than where we started means the
+		// desk reversed, either the safety feature kicked in or the user took
+		// control with the physical buttons, the same as direction moves. The
+		// desk is only stopped for the safety feature, not to fight the user.
+		if differenceAbs > startDifference+0.010 && time.Now().After(graceUntil) {
+			err := d.classifyReversal(direction, closest, request)
+			if errors.Is(err, ErrManualOverride) {
+				return request, err
+			}
+
+			return request, errors.Join(err, d.Stop())
+		}
+
+		if since > referenceStallDuration && time.Now().After(graceUntil) {
+			if !moved {
+				return request, errors.Join(referenceInputUnsupported, d.Stop())
+			}
+
+			log.Errorf("desk stopped short of target at %.3f", reading.height)
+			return request, errors.Join(d.safetyKickIn(direction, reading.height, request), d.Stop())
+		}
+
+		if _, err := referenceCharacteristic.WriteWithoutResponse(reference); err != nil {
+			return request, errors.Join(fmt.Errorf("%s: %w", bluetoothError.Error(), err), d.Stop())
+		}
+
+		time.Sleep(referenceInputInterval)
+	}
+}
+
+// moveToTargetDirection moves the desk by repeatedly sending the up and down
+// commands until the target is reached.
+func (d *Desk) moveToTargetDirection(request *moveRequest) (*moveRequest, error) {
+	previousHeight := d.lastReading().height
+	willMoveUp := request.target > previousHeight
+	var graceUntil time.Time
+
+	log.Infof("moving desk from %.2f to %.2f", previousHeight, request.target)
+
+	for {
+		loopHeight := d.lastReading().height
+
+		// A new target preempted the current one, continue towards the new
+		// target straight away. The desk is not stopped first, the next
+		// direction command turns it around.
+		if active := d.activeMove(); active != request {
+			if active == nil {
+				return request, d.Stop()
+			}
+
+			request = active
+			willMoveUp = request.target > loopHeight
+			graceUntil = time.Now().Add(preemptGracePeriod)
+
+			log.Infof("moving desk from %.2f to %.2f", loopHeight, request.target)
+			d.emitMoveStarted(request)
+		}
+
+		target := request.target
+		differenceRaw := target - loopHeight
+		differenceAbs := math.Abs(differenceRaw)
+
+		log.Debugf("target=%f, current_height=%f previous_height=%f, difference=%f",
+			target, loopHeight, previousHeight, differenceRaw)
+
+		// The device has a moving action to protect the user if it applies
+		// pressure to something when moving. This will result in the desk
+		// moving in the opposite direction when the device detects something.
+		// Moving out th way. The same happens when the user presses the
+		// physical buttons against our direction. If we detect this, stop
+		// sending commands and work out which one it was.
+		//
+		// Only if our difference is not nothing, meaning we are not doing a
+		// minor correction, and the desk is not still turning around after
+		// the target changed.
+		if (loopHeight < previousHeight && willMoveUp ||
+			loopHeight > previousHeight && !willMoveUp) &&
+			differenceAbs > 0.010 && time.Now().After(graceUntil) {
+			direction := DOWN
+			if willMoveUp {
+				direction = UP
+			}
+
+			return request, d.classifyReversal(direction, previousHeight, request)
+		}
+
+		// If we're either less than 10mm then we need to stop every iteration
+		// so that we don't overshoot
+		if differenceAbs < 0.010 {
+			log.Debugf("hit differnce made: height: %f - difference: %f",
+				loopHeight, differenceRaw)
+
+			if stopErr := d.Stop(); stopErr != nil {
+				return request, stopErr
+			}
+		}
+
+		// If we are within our tolerance for moving the desk then we can go and
+		// stop. Additionally ensure to stop first to keep in line with our
+		// tolerance. Otherwise, a shift in the difference could occur when
+		// pulling the final destination.
+		//
+		// within 5mm
+		if differenceAbs <= 0.005 {
+			if stopErr := d.Stop(); stopErr != nil {
+				return request, stopErr
+			}
+
+			// Sleep for the duration of a possible upper limit of a step
+			// duration. This duration was determined from a single `MOVE`
+			// operation.
+			time.Sleep(time.Millisecond * 100)
+			log.Infof("reached target of %.3f, actual: %.3f", target, d.lastReading().height)
+			return request, nil
+		}
+
+		operation := UP
+		if differenceRaw < 0.0 {
+			operation = DOWN
+		}
+
+		// Attempt to move into the correct direction, if it faults, attempt to
+		// stop and return the errors.
+		if err := d.moveDirection(operation); err != nil {
+			return request, errors.Join(err, d.Stop())
+		}
+
+		previousHeight = loopHeight
+	}
+}
+
+// MoveDirection Based on the provided direction, the desk will be told to start
+// moving up or start moving down. A move action will only occur for a 1-second
+// interval, which is configured by the desk.
+func (d *Desk) MoveDirection(direction Direction) error {
+	if err := d.checkGuard(d.source, fmt.Sprintf("move %s", direction), 0); err != nil {
+		return err
+	}
+
+	return d.moveDirection(direction)
+}
+
+func (d *Desk) moveDirection(direction Direction) error {
+	actionArgs := []uint8{0x47, 0x00}
+
+	if direction == DOWN {
+		actionArgs = []uint8{0x46, 0x00}
+	}
+
+	characteristic := d.getCharacteristic(UuidCommand)
+
+	if characteristic == nil {
+		return &CharacteristicError{UUID: UuidCommand}
+	}
+
+	if _, err := characteristic.WriteWithoutResponse(actionArgs); err != nil {
+		return fmt.Errorf("%s: %w", bluetoothError.Error(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package desk
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// heightReading is the latest height reported by the desk.
+type heightReading struct {
+	height float64
+	speed  float64
+	at     time.Time
+}
+
+// heightNotifier shares a single subscription to the height notifications of
+// the desk between all readers. Enabling notifications more than once on the
+// same characteristic would result in each reader getting duplicate updates.
+type heightNotifier struct {
+	// enableMu serialises enabling notifications, held while talking to the
+	// desk so must never be taken while holding connMu or mu.
+	enableMu sync.Mutex
+
+	mu          sync.RWMutex
+	enabled     bool
+	reading     heightReading
+	nextID      int
+	subscribers map[int]func(height, speed float64)
+}
+
+// SubscribeHeight calls the function with the height and speed (meters per
+// second) of every height notification from the desk, until the returned
+// function is called. Safe for concurrent use.
+func (d *Desk) SubscribeHeight(fn func(height, speed float64)) (func(), error) {
+	if err := d.enableHeightNotifications(); err != nil {
+		return nil, err
+	}
+
+	n := &d.heights
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.subscribers == nil {
+		n.subscribers = map[int]func(height, speed float64){}
+	}
+
+	id := n.nextID
+	n.nextID++
+	n.subscribers[id] = fn
+
+	return func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.subscribers, id)
+	}, nil
+}
+
+// lastReading returns the latest height reported by the desk. Height
+// notifications must be enabled for the reading to be kept up to date.
+func (d *Desk) lastReading() heightReading {
+	d.heights.mu.RLock()
+	defer d.heights.mu.RUnlock()
+	return d.heights.reading
+}
+
+// enableHeightNotifications enables the height notifications of the desk once
+// per connection, seeding the latest reading with a direct read.
+func (d *Desk) enableHeightNotifications() error {
+	n := &d.heights
+
+	n.enableMu.Lock()
+	defer n.enableMu.Unlock()
+
+	n.mu.RLock()
+	enabled := n.enabled
+	n.mu.RUnlock()
+
+	if enabled {
+		return nil
+	}
+
+	characteristic := d.getCharacteristic(UuidHeight)
+
+	if characteristic == nil {
+		return &CharacteristicError{UUID: UuidHeight}
+	}
+
+	height, err := d.GetHeight()
+	if err != nil {
+		return fmt.Errorf("failed to get desk height, %w", err)
+	}
+
+	n.mu.Lock()
+	n.reading = heightReading{height: height, at: time.Now()}
+	n.mu.Unlock()
+
+	if err = characteristic.EnableNotifications(d.handleHeightNotification); err != nil {
+		return fmt.Errorf("failed to configure desk hight notifications, %w", err)
+	}
+
+	n.mu.Lock()
+	n.enabled = true
+	n.mu.Unlock()
+
+	return nil
+}
+
+// resubscribeLocked re-enables height notifications after reconnecting if
+// they were enabled on the previous connection, so existing subscribers keep
+// receiving updates. connMu must be held.
+func (d *Desk) resubscribeLocked() {
+	if !d.resubscribe {
+		return
+	}
+
+	d.resubscribe = false
+
+	for _, characteristic := range d.serviceCharacteristics {
+		if characteristic.UUID() != UuidHeight {
+			continue
+		}
+
+		if err := characteristic.EnableNotifications(d.handleHeightNotification); err != nil {
+			log.WithError(err).Warn("failed to re-enable desk height notifications")
+			return
+		}
+
+		d.heights.mu.Lock()
+		d.heights.enabled = true
+		d.heights.mu.Unlock()
+		return
+	}
+}
+
+// resetNotificationsLocked marks notifications as disabled after
+// disconnecting, remembering to re-enable them on the next connection if they
+// were enabled. connMu must be held.
+func (d *Desk) resetNotificationsLocked() {
+	d.heights.mu.Lock()
+	defer d.heights.mu.Unlock()
+
+	d.resubscribe = d.heights.enabled
+	d.heights.enabled = false
+}
+
+func (d *Desk) handleHeightNotification(buf []byte) {
+	reading := heightReading{
+		height: d.bytesToMeters(buf),
+		speed:  bytesToSpeed(buf),
+		at:     time.Now(),
+	}
+
+	log.Debugf("desk height notification: %f", reading.height)
+
+	d.heights.mu.Lock()
+	d.heights.reading = reading
+
+	subscribers := make([]func(height, speed float64), 0, len(d.heights.subscribers))
+	for _, subscriber := range d.heights.subscribers {
+		subscribers = append(subscribers, subscriber)
+	}
+
+	d.heights.mu.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(reading.height, reading.speed)
+	}
+}
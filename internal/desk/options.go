@@ -0,0 +1,157 @@
+package desk
+
+import "time"
+
+// MoveMode determines how the desk is driven towards a target height.
+type MoveMode string
+
+const (
+	// MoveModeDirection repeatedly sends the up/down command bursts until the
+	// target is reached. Supported by every known firmware.
+	MoveModeDirection MoveMode = "direction"
+
+	// MoveModeReference writes the target to the reference input and lets the
+	// desk ramp to it itself, falling back to MoveModeDirection if the desk does
+	// not respond to it.
+	MoveModeReference MoveMode = "reference"
+)
+
+// Option configures optional behaviour of the Desk.
+type Option func(d *Desk)
+
+// WithMoveMode sets the mode used by MoveToTarget. Unknown values are treated
+// as MoveModeDirection.
+func WithMoveMode(mode MoveMode) Option {
+	return func(d *Desk) {
+		d.moveMode = mode
+	}
+}
+
+// WithReferenceMinFirmware only tries MoveModeReference on desks reporting a
+// firmware revision of at least the given version, e.g. "1.2", moving other
+// desks with MoveModeDirection straight away. Empty tries it on every desk.
+func WithReferenceMinFirmware(version string) Option {
+	return func(d *Desk) {
+		d.referenceMinFirmware = version
+	}
+}
+
+// WithWakeUp enables sending the wake-up command before every movement. Desk
+// controllers that have been idle can ignore the first command sent to them.
+func WithWakeUp(enabled bool) Option {
+	return func(d *Desk) {
+		d.wakeUp = enabled
+	}
+}
+
+// WithKeepAlive sets how often a keep-alive is sent to the desk in long-running
+// modes, e.g. Monitor. Zero disables the keep-alive.
+func WithKeepAlive(interval time.Duration) Option {
+	return func(d *Desk) {
+		d.keepAliveInterval = interval
+	}
+}
+
+// WithCharacteristics replaces the characteristics used to communicate with
+// the desk, e.g. with a fake transport when not connected.
+func WithCharacteristics(characteristics ...Characteristic) Option {
+	return func(d *Desk) {
+		d.serviceCharacteristics = characteristics
+	}
+}
+
+// WithIdleRelease sets how long the desk can be idle before the connection is
+// released, allowing other devices to connect. The next request reconnects.
+// Zero disables the idle release.
+func WithIdleRelease(duration time.Duration) Option {
+	return func(d *Desk) {
+		d.idleRelease = duration
+	}
+}
+
+// WithEventHandler registers a function called with every event emitted by the
+// desk, e.g. a move starting or the desk being moved manually. Handlers are
+// called synchronously and should not block.
+func WithEventHandler(handler func(event Event)) Option {
+	return func(d *Desk) {
+		d.eventHandlers = append(d.eventHandlers, handler)
+	}
+}
+
+// SafetyAction determines what happens after the desk safety feature stops an
+// automated move, e.g. after colliding with a chair.
+type SafetyAction string
+
+const (
+	// SafetyActionStop gives up on the move, leaving the desk where the safety
+	// feature stopped it.
+	SafetyActionStop SafetyAction = "stop"
+
+	// SafetyActionRetry waits and then moves towards the target again, giving
+	// up after the configured number of retries.
+	SafetyActionRetry SafetyAction = "retry"
+
+	// SafetyActionReturn moves the desk back to the height it started from.
+	SafetyActionReturn SafetyAction = "return"
+)
+
+// SafetyPolicy configures how MoveToTarget recovers from the desk safety
+// feature kicking in.
+type SafetyPolicy struct {
+	Action  SafetyAction
+	Retries int
+	Delay   time.Duration
+}
+
+// WithSafetyPolicy sets how MoveToTarget recovers from the desk safety feature
+// kicking in. Unknown actions are treated as SafetyActionStop.
+func WithSafetyPolicy(policy SafetyPolicy) Option {
+	return func(d *Desk) {
+		d.safety = policy
+	}
+}
+
+// WithSoftLimits narrows the heights the desk is moved to, e.g. to stop a
+// shorter user from raising the desk further than they need. Zero uses the
+// limit of the desk.
+func WithSoftLimits(minHeight, maxHeight float64) Option {
+	return func(d *Desk) {
+		d.softMinHeight = minHeight
+		d.softMaxHeight = maxHeight
+	}
+}
+
+// WithProfile attributes all events emitted by the desk to the named user
+// profile.
+func WithProfile(name string) Option {
+	return func(d *Desk) {
+		d.profile = name
+	}
+}
+
+// WithGuard registers a function checked before every movement of the desk,
+// which refuses the movement by returning an error, e.g. a *LockedError. A
+// guard can also block to delay the movement. Stopping the desk is always
+// allowed.
+func WithGuard(guard func() error) Option {
+	return func(d *Desk) {
+		d.guards = append(d.guards, guard)
+	}
+}
+
+// WithMoveGuard registers a function checked before every movement of the
+// desk the same as WithGuard, given the distance in meters the desk moves. The
+// distance is zero when it isn't known up front, e.g. when jogging.
+func WithMoveGuard(guard func(distance float64) error) Option {
+	return func(d *Desk) {
+		d.moveGuards = append(d.moveGuards, guard)
+	}
+}
+
+// WithSource describes who is controlling the desk, e.g. the command being
+// run, logged when a movement is refused by the guard.
+func WithSource(source string) Option {
+	return func(d *Desk) {
+		d.source = source
+	}
+}
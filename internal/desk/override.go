@@ -0,0 +1,96 @@
+package desk
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// reversalObservation is how long the desk is observed after it moved
+	// against the commanded direction, to tell the safety feature apart from a
+	// user holding the physical buttons.
+	reversalObservation = 1500 * time.Millisecond
+
+	// safetyReverseDistance is the furthest the safety feature is expected to
+	// reverse the desk. Anything further is the user moving the desk.
+	safetyReverseDistance = 0.05
+
+	// manualMotionWindow is how recently the desk must have reported a height
+	// change at the end of the observation to still be considered moving. The
+	// safety feature only reverses briefly, while a user holds the button.
+	manualMotionWindow = 300 * time.Millisecond
+)
+
+// classifyReversal is called once the desk moved against the commanded
+// direction of the request, from the given height. The desk safety feature briefly reverses
+// the desk when it hits something, while a user pressing the physical buttons
+// keeps it moving for as long as the button is held.
+//
+// Returns ErrManualOverride if the user took control of the desk, otherwise
+// ErrSafetyStop. No more commands are sent, so the observation does not count
+// as the motor being driven.
+func (d *Desk) classifyReversal(direction Direction, from float64, request *moveRequest) error {
+	d.stopDriving()
+
+	reversed := func(height float64) float64 {
+		if direction == UP {
+			return from - height
+		}
+
+		return height - from
+	}
+
+	deadline := time.Now().Add(reversalObservation)
+
+	for time.Now().Before(deadline) {
+		if reading := d.lastReading(); reversed(reading.height) > safetyReverseDistance {
+			return d.manualOverride(direction, reading.height, request)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if reading := d.lastReading(); time.Since(reading.at) < manualMotionWindow {
+		return d.manualOverride(direction, reading.height, request)
+	}
+
+	return d.safetyKickIn(direction, d.lastReading().height, request)
+}
+
+// safetyKickIn records that the desk safety feature stopped an automated move
+// request in the given direction, e.g. the desk colliding with a chair.
+func (d *Desk) safetyKickIn(direction Direction, height float64, request *moveRequest) error {
+	log.Errorf("stopped moving %s at %.3f because desk safety feature kicked in.", direction, height)
+
+	d.emit(Event{
+		Type:      EventSafetyKickIn,
+		Height:    height,
+		Target:    request.target,
+		Direction: direction.String(),
+		Profile:   request.profile,
+	})
+
+	return ErrSafetyStop
+}
+
+// manualOverride records that the user took control of the desk during an
+// automated move of the request in the given direction.
+func (d *Desk) manualOverride(direction Direction, height float64, request *moveRequest) error {
+	manual := UP
+	if direction == UP {
+		manual = DOWN
+	}
+
+	log.Warnf("desk moved %s manually at %.3f, aborting move to %.3f", manual, height, request.target)
+
+	d.emit(Event{
+		Type:      EventManualOverride,
+		Height:    height,
+		Target:    request.target,
+		Direction: manual.String(),
+		Profile:   request.profile,
+	})
+
+	return ErrManualOverride
+}
@@ -0,0 +1,37 @@
+package desk
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// UserIDLength is the length in bytes of the user ID registered with the desk.
+const UserIDLength = 16
+
+// NewUserID generates a random user ID used to register a controller with the
+// desk.
+func NewUserID() ([]byte, error) {
+	id := make([]byte, UserIDLength)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate user id, %w", err)
+	}
+
+	return id, nil
+}
+
+// RegisterUser registers this controller with the desk under the given user
+// ID. Linak desks expect a controller to register before some features, e.g.
+// memory positions, are available to it.
+func (d *Desk) RegisterUser(id []byte) error {
+	client, err := d.DPG()
+	if err != nil {
+		return err
+	}
+
+	if err = client.SetUserID(id); err != nil {
+		return fmt.Errorf("failed to register user id, %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package desk
+
+import (
+	"testing"
+	"time"
+)
+
+// directionCommands returns how many up or down commands were written.
+func directionCommands(f *fakeDesk) int {
+	count := 0
+	for _, write := range f.writesTo(UuidCommand) {
+		if write[0] == 0x47 || write[0] == 0x46 {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestReferenceMove(t *testing.T) {
+	f := newFakeDesk(0.75)
+	d := newTestDesk(f, WithMoveMode(MoveModeReference))
+
+	if err := d.MoveToTarget(0.80); err != nil {
+		t.Fatal(err)
+	}
+
+	assertHeight(t, f, 0.80)
+
+	if commands := directionCommands(f); commands != 0 {
+		t.Errorf("expected the desk to move by reference input only, got %d direction commands", commands)
+	}
+}
+
+// TestReferenceMoveAfterIdle moves the desk again once the last reading is
+// older than the stall duration, as in a long running session.
+func TestReferenceMoveAfterIdle(t *testing.T) {
+	f := newFakeDesk(0.75)
+	d := newTestDesk(f, WithMoveMode(MoveModeReference))
+
+	if err := d.MoveToTarget(0.78); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(referenceStallDuration + 200*time.Millisecond)
+
+	if err := d.MoveToTarget(0.74); err != nil {
+		t.Fatal(err)
+	}
+
+	assertHeight(t, f, 0.74)
+
+	if commands := directionCommands(f); commands != 0 {
+		t.Errorf("expected the desk to keep using the reference input, got %d direction commands", commands)
+	}
+}
+
+func TestReferenceMoveUnsupported(t *testing.T) {
+	f := newFakeDesk(0.75)
+	f.ignoreReference = true
+	d := newTestDesk(f, WithMoveMode(MoveModeReference))
+
+	if err := d.MoveToTarget(0.80); err != nil {
+		t.Fatal(err)
+	}
+
+	assertHeight(t, f, 0.80)
+
+	if directionCommands(f) == 0 {
+		t.Error("expected the move to fall back to direction commands")
+	}
+}
+
+func TestReferenceMinFirmware(t *testing.T) {
+	tests := []struct {
+		name      string
+		firmware  string
+		reference bool
+	}{
+		{"new enough", "V1.3", true},
+		{"too old", "V1.1.9", false},
+		{"not reported", "", false},
+	}
+
+	for _, test := range tests {
+		f := newFakeDesk(0.75)
+		f.firmware = test.firmware
+		d := newTestDesk(f, WithMoveMode(MoveModeReference), WithReferenceMinFirmware("1.2"))
+
+		if err := d.MoveToTarget(0.80); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		assertHeight(t, f, 0.80)
+
+		if reference := directionCommands(f) == 0; reference != test.reference {
+			t.Errorf("%s: expected moving by reference input %v, got %d direction commands",
+				test.name, test.reference, directionCommands(f))
+		}
+	}
+}
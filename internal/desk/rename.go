@@ -0,0 +1,83 @@
+package desk
+
+import (
+	"fmt"
+	"time"
+
+	"tinygo.org/x/bluetooth"
+)
+
+// maxDeviceNameLength is the maximum length of the GAP device name as defined
+// by the bluetooth specification.
+const maxDeviceNameLength = 248
+
+// reconnectDelay is how long to wait after disconnecting before connecting
+// again, giving the desk time to drop the previous connection.
+const reconnectDelay = 2 * time.Second
+
+// requestWriter is implemented by characteristics able to explicitly write
+// with a response, e.g. on Windows.
+type requestWriter interface {
+	Write(p []byte) (int, error)
+}
+
+// Rename writes the advertised device name of the desk. The desk only applies
+// the new name to new connections, so the desk is reconnected and the name read
+// back, returning an error if the desk did not take the new name.
+func (d *Desk) Rename(name string) error {
+	if name == "" {
+		return fmt.Errorf("desk name cannot be empty")
+	}
+
+	if len(name) > maxDeviceNameLength {
+		return fmt.Errorf("desk name cannot exceed %d bytes", maxDeviceNameLength)
+	}
+
+	characteristic := d.getCharacteristic(bluetooth.CharacteristicUUIDDeviceName)
+
+	if characteristic == nil {
+		return &CharacteristicError{UUID: bluetooth.CharacteristicUUIDDeviceName}
+	}
+
+	if _, err := writeRequest(characteristic, []byte(name)); err != nil {
+		return fmt.Errorf("%s: %w", bluetoothError.Error(), err)
+	}
+
+	if err := d.Reconnect(); err != nil {
+		return fmt.Errorf("failed to reconnect to desk after rename, %w", err)
+	}
+
+	if actual := d.readString(bluetooth.CharacteristicUUIDDeviceName); actual != name {
+		return fmt.Errorf("desk reports name %q after rename, expected %q", actual, name)
+	}
+
+	d.name = name
+	return nil
+}
+
+// writeRequest writes the value with a write request, which the GAP device
+// name characteristic requires. Where the transport does not offer an explicit
+// write request, the platform picks the write type the characteristic
+// supports, e.g. BlueZ uses a write request.
+func writeRequest(characteristic Characteristic, p []byte) (int, error) {
+	if writer, ok := characteristic.(requestWriter); ok {
+		return writer.Write(p)
+	}
+
+	return characteristic.WriteWithoutResponse(p)
+}
+
+// Reconnect drops the current connection to the desk and connects again,
+// rediscovering all services and characteristics.
+func (d *Desk) Reconnect() error {
+	d.connMu.Lock()
+	err := d.disconnectLocked()
+	d.connMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	time.Sleep(reconnectDelay)
+	return d.Connect()
+}
@@ -0,0 +1,92 @@
+package desk
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// moveWithSafetyPolicy moves the desk towards the request, recovering with the
+// configured safety policy if the desk safety feature kicks in. Returns the
+// request that was active when the move completed, the same as moveTo.
+func (d *Desk) moveWithSafetyPolicy(request *moveRequest) (*moveRequest, error) {
+	attempt := 0
+
+	for {
+		active, err := d.moveTo(request)
+
+		// Preempted requests are completed by the loop moving towards the new
+		// target, there is nothing left to recover.
+		if !errors.Is(err, ErrSafetyStop) || active != d.activeMove() {
+			return active, err
+		}
+
+		switch d.safety.Action {
+		case SafetyActionRetry:
+			if attempt >= d.safety.Retries {
+				log.Errorf("giving up on move to %.2f after %d retries", active.target, attempt)
+				return active, err
+			}
+
+			attempt++
+			log.Warnf("retrying move to %.2f in %s (%d/%d)", active.target, d.safety.Delay, attempt, d.safety.Retries)
+			time.Sleep(d.safety.Delay)
+
+			// A new target could have arrived while waiting, which is a new
+			// move and gets its own retries.
+			if request = d.activeMove(); request == nil {
+				return active, err
+			} else if request != active {
+				attempt = 0
+			}
+
+			d.emitMoveStarted(request)
+		case SafetyActionReturn:
+			return d.returnToStart(active, err)
+		default:
+			return active, err
+		}
+	}
+}
+
+// returnToStart moves the desk back to the height the request started from
+// after the safety feature kicked in. The request still fails with the given
+// error once the desk is back, as the target was never reached.
+//
+// The return is a move of its own, so a new target preempts it as usual, in
+// which case the request is completed as preempted and the new request is
+// returned instead.
+func (d *Desk) returnToStart(request *moveRequest, safetyErr error) (*moveRequest, error) {
+	back := &moveRequest{target: request.from, started: time.Now(), done: make(chan error, 1), profile: request.profile}
+
+	d.moves.mu.Lock()
+	if d.moves.active != request {
+		d.moves.mu.Unlock()
+		return request, safetyErr
+	}
+
+	d.moves.active = back
+	d.moves.mu.Unlock()
+
+	log.Infof("returning desk to start height %.2f after safety kick-in", back.target)
+
+	d.emitMoveStarted(back)
+	active, err := d.moveTo(back)
+
+	d.moves.mu.Lock()
+	returned := d.moves.active == back
+	if returned {
+		d.moves.active = request
+	}
+	d.moves.mu.Unlock()
+
+	if returned {
+		return request, errors.Join(safetyErr, err)
+	}
+
+	// A new target preempted the return or it was cancelled, which was sent to
+	// the return request, so pass it on to the request waiting for it.
+	request.done <- <-back.done
+	return active, err
+}
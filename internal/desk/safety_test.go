@@ -0,0 +1,157 @@
+package desk
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventRecorder records the events emitted by a desk.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) record(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// count returns how many events of the type were emitted.
+func (r *eventRecorder) count(eventType EventType) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, event := range r.events {
+		if event.Type == eventType {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestSafetyActionStop(t *testing.T) {
+	f := newFakeDesk(0.75)
+	f.placeObstacle(0.90, 1)
+
+	events := &eventRecorder{}
+	d := newTestDesk(f, WithEventHandler(events.record), WithSafetyPolicy(SafetyPolicy{Action: SafetyActionStop}))
+
+	if err := d.MoveToTarget(1.10); !errors.Is(err, ErrSafetyStop) {
+		t.Fatalf("expected %v, got %v", ErrSafetyStop, err)
+	}
+
+	if kickIns := events.count(EventSafetyKickIn); kickIns != 1 {
+		t.Errorf("expected a single safety kick-in, got %d", kickIns)
+	}
+
+	if started := events.count(EventMoveStarted); started != 1 {
+		t.Errorf("expected no further moves, got %d", started)
+	}
+
+	assertHeight(t, f, 0.88)
+}
+
+func TestSafetyActionRetry(t *testing.T) {
+	f := newFakeDesk(0.75)
+	f.placeObstacle(0.90, 1)
+
+	events := &eventRecorder{}
+	d := newTestDesk(f, WithEventHandler(events.record), WithSafetyPolicy(SafetyPolicy{
+		Action:  SafetyActionRetry,
+		Retries: 2,
+		Delay:   10 * time.Millisecond,
+	}))
+
+	if err := d.MoveToTarget(1.10); err != nil {
+		t.Fatalf("expected the retry to reach the target, got %v", err)
+	}
+
+	if kickIns := events.count(EventSafetyKickIn); kickIns != 1 {
+		t.Errorf("expected a single safety kick-in, got %d", kickIns)
+	}
+
+	if started := events.count(EventMoveStarted); started != 2 {
+		t.Errorf("expected the move to be started again, got %d starts", started)
+	}
+
+	assertHeight(t, f, 1.10)
+}
+
+func TestSafetyActionRetryGivesUp(t *testing.T) {
+	f := newFakeDesk(0.75)
+	f.placeObstacle(0.90, 10)
+
+	events := &eventRecorder{}
+	d := newTestDesk(f, WithEventHandler(events.record), WithSafetyPolicy(SafetyPolicy{
+		Action:  SafetyActionRetry,
+		Retries: 1,
+		Delay:   10 * time.Millisecond,
+	}))
+
+	if err := d.MoveToTarget(1.10); !errors.Is(err, ErrSafetyStop) {
+		t.Fatalf("expected %v after the retries, got %v", ErrSafetyStop, err)
+	}
+
+	if kickIns := events.count(EventSafetyKickIn); kickIns != 2 {
+		t.Errorf("expected a safety kick-in for the move and its retry, got %d", kickIns)
+	}
+}
+
+func TestSafetyActionReturn(t *testing.T) {
+	f := newFakeDesk(0.75)
+	f.placeObstacle(0.90, 1)
+
+	events := &eventRecorder{}
+	d := newTestDesk(f, WithEventHandler(events.record), WithSafetyPolicy(SafetyPolicy{Action: SafetyActionReturn}))
+
+	if err := d.MoveToTarget(1.10); !errors.Is(err, ErrSafetyStop) {
+		t.Fatalf("expected %v once returned, got %v", ErrSafetyStop, err)
+	}
+
+	if kickIns := events.count(EventSafetyKickIn); kickIns != 1 {
+		t.Errorf("expected a single safety kick-in, got %d", kickIns)
+	}
+
+	assertHeight(t, f, 0.75)
+}
+
+// TestMotorStoppedDuration expects only the time the desk was driven to count
+// towards how long the motor ran, not waking the desk, observing the reversal
+// or waiting to retry.
+func TestMotorStoppedDuration(t *testing.T) {
+	f := newFakeDesk(0.75)
+	f.placeObstacle(0.90, 1)
+
+	events := &eventRecorder{}
+	d := newTestDesk(f, WithEventHandler(events.record), WithWakeUp(true), WithSafetyPolicy(SafetyPolicy{
+		Action:  SafetyActionRetry,
+		Retries: 1,
+		Delay:   time.Second,
+	}))
+
+	started := time.Now()
+	if err := d.MoveToTarget(1.10); err != nil {
+		t.Fatalf("expected the retry to reach the target, got %v", err)
+	}
+
+	elapsed := time.Since(started)
+
+	events.mu.Lock()
+	defer events.mu.Unlock()
+
+	var driven time.Duration
+	for _, event := range events.events {
+		if event.Type == EventMotorStopped {
+			driven = event.Duration
+		}
+	}
+
+	if excluded := wakeUpDelay + reversalObservation + time.Second; driven <= 0 || driven > elapsed-excluded {
+		t.Errorf("expected at most %s driven of %s, got %s", elapsed-excluded, elapsed, driven)
+	}
+}
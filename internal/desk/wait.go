@@ -0,0 +1,85 @@
+package desk
+
+import (
+	"time"
+)
+
+const (
+	// waitIdleDuration is how long the desk has to go without reporting a
+	// height change to be considered idle.
+	waitIdleDuration = 500 * time.Millisecond
+
+	// waitPollInterval is how often the condition is checked without a height
+	// notification, as the desk stops notifying once it is idle.
+	waitPollInterval = 100 * time.Millisecond
+)
+
+// WaitUntilIdle blocks until the desk has stopped moving, returning the height
+// it stopped at. Returns ErrTimeout if the desk is still moving after the
+// timeout, zero waits forever.
+func (d *Desk) WaitUntilIdle(timeout time.Duration) (float64, error) {
+	return d.waitUntil(func(reading heightReading) bool {
+		return time.Since(reading.at) >= waitIdleDuration
+	}, timeout)
+}
+
+// WaitUntilAbove blocks until the desk has reached or moved above the height,
+// returning the height of the desk at the time. Returns ErrTimeout if the desk
+// is still below the height after the timeout, zero waits forever.
+func (d *Desk) WaitUntilAbove(height float64, timeout time.Duration) (float64, error) {
+	return d.waitUntil(func(reading heightReading) bool {
+		return reading.height >= height
+	}, timeout)
+}
+
+// WaitUntilBelow blocks until the desk has reached or moved below the height,
+// returning the height of the desk at the time. Returns ErrTimeout if the desk
+// is still above the height after the timeout, zero waits forever.
+func (d *Desk) WaitUntilBelow(height float64, timeout time.Duration) (float64, error) {
+	return d.waitUntil(func(reading heightReading) bool {
+		return reading.height <= height
+	}, timeout)
+}
+
+// waitUntil blocks until the condition holds for the latest height reported
+// by the desk, checked on every height notification.
+func (d *Desk) waitUntil(condition func(reading heightReading) bool, timeout time.Duration) (float64, error) {
+	updates := make(chan struct{}, 1)
+
+	unsubscribe, err := d.SubscribeHeight(func(_, _ float64) {
+		select {
+		case updates <- struct{}{}:
+		default:
+		}
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer unsubscribe()
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		reading := d.lastReading()
+		if condition(reading) {
+			return reading.height, nil
+		}
+
+		select {
+		case <-updates:
+		case <-ticker.C:
+		case <-deadline:
+			return reading.height, ErrTimeout
+		}
+	}
+}
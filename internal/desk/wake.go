@@ -0,0 +1,74 @@
+package desk
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var commandWakeUp = []byte{0xFE, 0x00}
+
+// wakeUpDelay is how long to wait after waking the desk for the controller to
+// accept commands.
+const wakeUpDelay = 100 * time.Millisecond
+
+// WakeUp sends the wake-up command to the desk controller, ensuring it does not
+// ignore the commands that follow after it has been idle.
+func (d *Desk) WakeUp() error {
+	characteristic := d.getCharacteristic(UuidCommand)
+
+	if characteristic == nil {
+		return &CharacteristicError{UUID: UuidCommand}
+	}
+
+	if _, err := characteristic.WriteWithoutResponse(commandWakeUp); err != nil {
+		return fmt.Errorf("%s: %w", bluetoothError.Error(), err)
+	}
+
+	time.Sleep(wakeUpDelay)
+	return nil
+}
+
+// prepareMove wakes the desk before a movement if configured to do so.
+func (d *Desk) prepareMove() error {
+	if !d.wakeUp {
+		return nil
+	}
+
+	log.Debug("waking desk before move")
+	return d.WakeUp()
+}
+
+// KeepAlive periodically wakes the desk and reads its height to hold the
+// connection open, until done is closed. Does nothing if no keep-alive
+// interval is configured. Keep-alives do not count as activity for the idle
+// release and are skipped while the connection is released.
+func (d *Desk) KeepAlive(done <-chan struct{}) {
+	if d.keepAliveInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(d.keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			d.withoutActivity(func() {
+				log.Debug("sending desk keep-alive")
+
+				if err := d.WakeUp(); err != nil {
+					log.WithError(err).Warn("failed to send desk keep-alive")
+					return
+				}
+
+				if _, err := d.GetHeight(); err != nil {
+					log.WithError(err).Warn("failed to read desk height during keep-alive")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package desk
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWakeUpBeforeMove(t *testing.T) {
+	f := newFakeDesk(0.75)
+	d := newTestDesk(f, WithWakeUp(true))
+
+	if err := d.MoveToTarget(0.80); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := f.writesTo(UuidCommand)
+	if len(commands) < 2 {
+		t.Fatalf("expected the wake-up and move commands, got % x", commands)
+	}
+
+	if !bytes.Equal(commands[0], commandWakeUp) {
+		t.Errorf("expected the wake-up command first, got % x", commands[0])
+	}
+
+	if !bytes.Equal(commands[1], []byte{0x47, 0x00}) {
+		t.Errorf("expected the up command after waking, got % x", commands[1])
+	}
+
+	assertHeight(t, f, 0.80)
+}
+
+func TestNoWakeUpBeforeMove(t *testing.T) {
+	f := newFakeDesk(0.75)
+	d := newTestDesk(f, WithWakeUp(false))
+
+	if err := d.MoveToTarget(0.80); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, command := range f.writesTo(UuidCommand) {
+		if bytes.Equal(command, commandWakeUp) {
+			t.Fatal("expected no wake-up command")
+		}
+	}
+}
+
+func TestKeepAliveTicksAtInterval(t *testing.T) {
+	f := newFakeDesk(0.75)
+	d := newTestDesk(f, WithKeepAlive(200*time.Millisecond))
+
+	d.connMu.Lock()
+	lastActivity := d.lastActivity
+	d.connMu.Unlock()
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		d.KeepAlive(done)
+		close(stopped)
+	}()
+
+	// Ticks at 200ms, 400ms and 600ms.
+	time.Sleep(700 * time.Millisecond)
+	close(done)
+	<-stopped
+
+	wakeUps := len(f.writesTo(UuidCommand))
+	if wakeUps < 2 || wakeUps > 4 {
+		t.Errorf("expected 3 keep-alives, got %d", wakeUps)
+	}
+
+	if reads := f.readCount(); reads != wakeUps {
+		t.Errorf("expected a height read with every keep-alive, got %d reads for %d keep-alives", reads, wakeUps)
+	}
+
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
+
+	if !d.lastActivity.Equal(lastActivity) {
+		t.Error("expected keep-alives not to count as activity")
+	}
+}
+
+func TestKeepAliveSkippedWhenReleased(t *testing.T) {
+	f := newFakeDesk(0.75)
+	d := newTestDesk(f, WithKeepAlive(20*time.Millisecond))
+
+	d.connMu.Lock()
+	d.released = true
+	d.connMu.Unlock()
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		d.KeepAlive(done)
+		close(stopped)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	close(done)
+	<-stopped
+
+	if writes := f.writesTo(UuidCommand); len(writes) != 0 {
+		t.Errorf("expected no keep-alives while released, got % x", writes)
+	}
+}
+
+func TestKeepAliveDisabled(t *testing.T) {
+	d := newTestDesk(newFakeDesk(0.75))
+
+	stopped := make(chan struct{})
+
+	go func() {
+		d.KeepAlive(make(chan struct{}))
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("expected KeepAlive to return straight away without an interval")
+	}
+}
@@ -0,0 +1,220 @@
+// Package duty protects the motors of the desk from running longer than their
+// rated duty cycle, e.g. 2 minutes on per 18 minutes off for Linak actuators.
+//
+// The time the motor ran is taken from the motor stopped events recorded in
+// the history, so it is tracked across processes.
+package duty
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"idasen-desk/internal/desk"
+	"idasen-desk/internal/history"
+)
+
+// Action determines what happens to a move once the duty cycle is used up.
+type Action string
+
+const (
+	// ActionRefuse fails the move with an *ExceededError.
+	ActionRefuse Action = "refuse"
+
+	// ActionDelay waits until the duty cycle has budget again before moving.
+	ActionDelay Action = "delay"
+)
+
+// availabilityResolution is how precisely the time the budget becomes
+// available again is calculated.
+const availabilityResolution = time.Second
+
+// ErrExceeded is returned when the motor has used up its duty cycle. The
+// returned error is an *ExceededError with the details.
+var ErrExceeded = errors.New("motor duty cycle exceeded")
+
+// ExceededError is returned when a move is refused as the motor has used up
+// its duty cycle, or the move would run the motor for longer than remains.
+type ExceededError struct {
+	Budget Budget
+
+	// Move is the estimated time the refused move runs the motor for, zero if
+	// it isn't known up front.
+	Move time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	var move string
+	if e.Move > 0 {
+		move = fmt.Sprintf(", the move needs about %s", e.Move.Round(time.Second))
+	}
+
+	return fmt.Sprintf("%s (%s of %s used in the last %s%s), available again at %s",
+		ErrExceeded.Error(), e.Budget.Used.Round(time.Second), e.Budget.Limit, e.Budget.Window, move,
+		e.Budget.Available.Format("15:04:05"))
+}
+
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrExceeded
+}
+
+// Budget is the state of the duty cycle at a point in time.
+type Budget struct {
+	Used      time.Duration `json:"used"`
+	Remaining time.Duration `json:"remaining"`
+	Limit     time.Duration `json:"limit"`
+	Window    time.Duration `json:"window"`
+
+	// Available is when the motor can run again, the time of the budget if it
+	// has budget remaining.
+	Available time.Time `json:"available"`
+}
+
+// Cycle tracks the duty cycle of the motor from the history.
+type Cycle struct {
+	history *history.History
+	limit   time.Duration
+	window  time.Duration
+	action  Action
+}
+
+// New creates a duty cycle allowing the motor to run for limit within any
+// window, using the events recorded within the history.
+func New(h *history.History, limit, window time.Duration, action Action) *Cycle {
+	return &Cycle{history: h, limit: limit, window: window, action: action}
+}
+
+// Budget returns the state of the duty cycle at the given time.
+func (c *Cycle) Budget(now time.Time) (Budget, error) {
+	return c.budget(now, 0)
+}
+
+// budget returns the state of the duty cycle at the given time, with the time
+// the motor can run for the move again as Available if it can't now.
+func (c *Cycle) budget(now time.Time, move time.Duration) (Budget, error) {
+	events, err := c.history.Since(now.Add(-c.window))
+	if err != nil {
+		return Budget{}, fmt.Errorf("failed to read movement history, %w", err)
+	}
+
+	var runs []run
+	for _, event := range events {
+		if event.Type == desk.EventMotorStopped && event.Duration > 0 {
+			runs = append(runs, run{start: event.Time.Add(-event.Duration), end: event.Time})
+		}
+	}
+
+	budget := Budget{Used: c.used(runs, now), Limit: c.limit, Window: c.window, Available: now}
+	budget.Remaining = max(0, c.limit-budget.Used)
+
+	if !c.fits(budget.Used, move) {
+		budget.Available = c.available(runs, now, move)
+	}
+
+	return budget, nil
+}
+
+// Check refuses or delays a move of the distance in meters once the motor has
+// used up its duty cycle, or the move would run the motor for longer than
+// remains, depending on the action. A distance of zero is a move of unknown
+// length, only refused once the duty cycle is used up. Used as a guard of the
+// desk through desk.WithMoveGuard.
+func (c *Cycle) Check(distance float64) error {
+	err := c.Allow(distance)
+
+	var exceeded *ExceededError
+	if c.action != ActionDelay || !errors.As(err, &exceeded) {
+		return err
+	}
+
+	log.Warnf("motor duty cycle used up, delaying move until %s", exceeded.Budget.Available.Format("15:04:05"))
+	time.Sleep(time.Until(exceeded.Budget.Available))
+
+	return nil
+}
+
+// Allow returns an *ExceededError if a move of the distance in meters would
+// exceed the duty cycle, the same as Check without delaying the move whatever
+// the action, e.g. to explain whether a move would be refused.
+func (c *Cycle) Allow(distance float64) error {
+	move := estimate(distance)
+
+	budget, err := c.budget(time.Now(), move)
+	if err != nil {
+		return err
+	}
+
+	if !c.fits(budget.Used, move) {
+		return &ExceededError{Budget: budget, Move: move}
+	}
+
+	log.Debugf("motor duty cycle: %s of %s remaining, move needs about %s",
+		budget.Remaining.Round(time.Second), budget.Limit, move.Round(time.Second))
+
+	return nil
+}
+
+// estimate returns how long the motor runs for to move the distance in meters.
+func estimate(distance float64) time.Duration {
+	return time.Duration(math.Abs(distance) / desk.NominalSpeed * float64(time.Second))
+}
+
+// fits returns true if the motor can run for the move with the time already
+// used. A move longer than the whole on time fits once nothing is used, it
+// would never fit otherwise.
+func (c *Cycle) fits(used, move time.Duration) bool {
+	remaining := c.limit - used
+	return remaining > 0 && remaining >= min(move, c.limit)
+}
+
+// run is a period of time the motor ran for.
+type run struct {
+	start time.Time
+	end   time.Time
+}
+
+// used returns how long the motor ran within the window ending at the time.
+func (c *Cycle) used(runs []run, at time.Time) time.Duration {
+	from := at.Add(-c.window)
+	var used time.Duration
+
+	for _, r := range runs {
+		start, end := r.start, r.end
+
+		if start.Before(from) {
+			start = from
+		}
+
+		if end.After(at) {
+			end = at
+		}
+
+		if end.After(start) {
+			used += end.Sub(start)
+		}
+	}
+
+	return used
+}
+
+// available returns the earliest time after now the motor has budget for the
+// move again. No run ends after now, so the time used only decreases as the
+// window moves on and the earliest time can be searched for.
+func (c *Cycle) available(runs []run, now time.Time, move time.Duration) time.Time {
+	low, high := now, now.Add(c.window)
+
+	for high.Sub(low) > availabilityResolution {
+		middle := low.Add(high.Sub(low) / 2)
+
+		if c.fits(c.used(runs, middle), move) {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+
+	return high
+}
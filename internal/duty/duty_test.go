@@ -0,0 +1,171 @@
+package duty
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"idasen-desk/internal/desk"
+	"idasen-desk/internal/history"
+)
+
+var now = time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+
+// ranFor returns a run ending the given time before now.
+func ranFor(duration, before time.Duration) run {
+	end := now.Add(-before)
+	return run{start: end.Add(-duration), end: end}
+}
+
+func TestUsed(t *testing.T) {
+	cycle := New(nil, 2*time.Minute, 20*time.Minute, ActionRefuse)
+
+	tests := []struct {
+		name     string
+		runs     []run
+		expected time.Duration
+	}{
+		{"no runs", nil, 0},
+		{"within the window", []run{ranFor(30*time.Second, time.Minute), ranFor(time.Minute, 5*time.Minute)}, 90 * time.Second},
+		{"ending at now", []run{ranFor(10*time.Second, 0)}, 10 * time.Second},
+		{"ending at the start of the window", []run{ranFor(time.Minute, 20*time.Minute)}, 0},
+		{"before the window", []run{ranFor(time.Minute, 30*time.Minute)}, 0},
+		{"across the start of the window", []run{ranFor(time.Minute, 19*time.Minute+30*time.Second)}, 30 * time.Second},
+	}
+
+	for _, test := range tests {
+		if used := cycle.used(test.runs, now); used != test.expected {
+			t.Errorf("%s: expected %s used, got %s", test.name, test.expected, used)
+		}
+	}
+}
+
+// TestAvailable expects the time to be found within the resolution after the
+// motor has budget again.
+func TestAvailable(t *testing.T) {
+	cycle := New(nil, 2*time.Minute, 20*time.Minute, ActionRefuse)
+
+	tests := []struct {
+		name     string
+		runs     []run
+		move     time.Duration
+		expected time.Time
+	}{
+		{
+			// The run leaves the window once it ended 20 minutes ago.
+			name:     "single run",
+			runs:     []run{ranFor(2*time.Minute, 0)},
+			expected: now.Add(18 * time.Minute),
+		},
+		{
+			// The oldest run only needs to partially leave the window.
+			name:     "partially leaving the window",
+			runs:     []run{ranFor(time.Minute, 10*time.Minute), ranFor(time.Minute, 0)},
+			expected: now.Add(9 * time.Minute),
+		},
+		{
+			name:     "budget for the move",
+			runs:     []run{ranFor(2*time.Minute, 0)},
+			move:     30 * time.Second,
+			expected: now.Add(18*time.Minute + 30*time.Second),
+		},
+		{
+			// Longer than the whole on time, it waits for an empty window.
+			name:     "longer than the on time",
+			runs:     []run{ranFor(time.Minute, 0)},
+			move:     5 * time.Minute,
+			expected: now.Add(20 * time.Minute),
+		},
+	}
+
+	for _, test := range tests {
+		available := cycle.available(test.runs, now, test.move)
+
+		if available.Before(test.expected) || available.After(test.expected.Add(availabilityResolution)) {
+			t.Errorf("%s: expected available at %s, got %s", test.name,
+				test.expected.Format(time.TimeOnly), available.Format(time.TimeOnly))
+		}
+	}
+}
+
+func record(t *testing.T, h *history.History, duration, before time.Duration) {
+	t.Helper()
+
+	event := desk.Event{Type: desk.EventMotorStopped, Time: time.Now().Add(-before), Duration: duration}
+	if err := h.Append(event); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	h := history.New(filepath.Join(t.TempDir(), "history.jsonl"))
+	record(t, h, 30*time.Second, 5*time.Minute)
+	record(t, h, time.Minute, 30*time.Minute)
+
+	if err := h.Append(desk.Event{Type: desk.EventTargetReached, Time: time.Now(), Duration: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+
+	budget, err := New(h, 2*time.Minute, 20*time.Minute, ActionRefuse).Budget(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if budget.Used != 30*time.Second || budget.Remaining != 90*time.Second {
+		t.Errorf("expected 30s used and 1m30s remaining, got %+v", budget)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	h := history.New(filepath.Join(t.TempDir(), "history.jsonl"))
+	record(t, h, 2*time.Minute-time.Second, time.Minute)
+
+	cycle := New(h, 2*time.Minute, 20*time.Minute, ActionRefuse)
+
+	tests := []struct {
+		name     string
+		distance float64
+		refused  bool
+	}{
+		{"unknown distance", 0, false},
+		{"short move", 0.02, false},
+		{"longer than remaining", 0.4, true},
+		{"down", -0.4, true},
+	}
+
+	for _, test := range tests {
+		err := cycle.Check(test.distance)
+
+		var exceeded *ExceededError
+		if errors.As(err, &exceeded) != test.refused {
+			t.Errorf("%s: expected refused %v, got %v", test.name, test.refused, err)
+		}
+
+		if test.refused && !errors.Is(err, ErrExceeded) {
+			t.Errorf("%s: expected ErrExceeded, got %v", test.name, err)
+		}
+	}
+}
+
+func TestCheckUsedUp(t *testing.T) {
+	h := history.New(filepath.Join(t.TempDir(), "history.jsonl"))
+	record(t, h, 2*time.Minute, time.Minute)
+
+	if err := New(h, 2*time.Minute, 20*time.Minute, ActionRefuse).Check(0); !errors.Is(err, ErrExceeded) {
+		t.Errorf("expected ErrExceeded once used up, got %v", err)
+	}
+}
+
+func TestAllowNeverDelays(t *testing.T) {
+	h := history.New(filepath.Join(t.TempDir(), "history.jsonl"))
+	record(t, h, 2*time.Minute, time.Minute)
+
+	started := time.Now()
+	err := New(h, 2*time.Minute, 20*time.Minute, ActionDelay).Allow(0.1)
+
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) || time.Since(started) > time.Second {
+		t.Errorf("expected an immediate *ExceededError, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Package ergonomics recommends desk and monitor heights from body
+// measurements, using anthropometric ratios of the stature of an average
+// adult, e.g. the ratios in Pheasant's Bodyspace.
+package ergonomics
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// standingElbowRatio is the height of the elbow from the floor when
+	// standing, relative to stature.
+	standingElbowRatio = 0.630
+
+	// poplitealRatio is the height of the back of the knee from the floor
+	// when sitting, relative to stature, which is the ideal seat height.
+	poplitealRatio = 0.250
+
+	// sittingElbowRatio is the height of the elbow above the seat, relative
+	// to stature.
+	sittingElbowRatio = 0.139
+
+	// standingEyeRatio is the height of the eyes from the floor when
+	// standing, relative to stature.
+	standingEyeRatio = 0.936
+
+	// sittingEyeRatio is the height of the eyes above the seat, relative to
+	// stature.
+	sittingEyeRatio = 0.454
+
+	// keyboardAllowance is how far below the standing elbow height the desk is
+	// recommended, leaving room for the keyboard with straight wrists.
+	keyboardAllowance = 0.020
+
+	// shoeAllowance is added to heights from the floor, as the ratios are for
+	// barefoot measurements.
+	shoeAllowance = 0.025
+)
+
+// ErrNoMeasurements is returned when neither the body height nor the elbow
+// height is known.
+var ErrNoMeasurements = errors.New("body height or elbow height is required")
+
+// Measurements are the body measurements of a person in meters. Either is
+// enough, the elbow height is preferred as it is closer to the desk height.
+type Measurements struct {
+	// Height is the stature of the person, barefoot.
+	Height float64
+
+	// ElbowHeight is the height of the elbow from the floor when standing with
+	// the upper arm hanging down, in shoes.
+	ElbowHeight float64
+}
+
+// Recommendation are the recommended heights in meters from the floor.
+type Recommendation struct {
+	SitHeight   float64 `json:"sit_height"`
+	StandHeight float64 `json:"stand_height"`
+
+	// SitMonitorHeight and StandMonitorHeight are the recommended heights of
+	// the top of the monitor, level with the eyes.
+	SitMonitorHeight   float64 `json:"sit_monitor_height"`
+	StandMonitorHeight float64 `json:"stand_monitor_height"`
+}
+
+// Recommend returns the recommended desk and monitor heights for the
+// measurements. The desk is at elbow height with the forearms horizontal,
+// sitting on a chair set to the height of the back of the knee, and slightly
+// below the elbows when standing.
+func Recommend(m Measurements) (Recommendation, error) {
+	if m.Height < 0 || m.ElbowHeight < 0 {
+		return Recommendation{}, fmt.Errorf("measurements cannot be negative")
+	}
+
+	stature := m.Height
+	standingElbow := m.ElbowHeight
+
+	switch {
+	case stature == 0 && standingElbow == 0:
+		return Recommendation{}, ErrNoMeasurements
+	case stature == 0:
+		stature = (standingElbow - shoeAllowance) / standingElbowRatio
+	case standingElbow == 0:
+		standingElbow = stature*standingElbowRatio + shoeAllowance
+	}
+
+	seat := stature*poplitealRatio + shoeAllowance
+
+	return Recommendation{
+		SitHeight:          seat + stature*sittingElbowRatio,
+		StandHeight:        standingElbow - keyboardAllowance,
+		SitMonitorHeight:   seat + stature*sittingEyeRatio,
+		StandMonitorHeight: stature*standingEyeRatio + shoeAllowance,
+	}, nil
+}
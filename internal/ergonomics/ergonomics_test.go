@@ -0,0 +1,75 @@
+package ergonomics
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestRecommend(t *testing.T) {
+	tests := []struct {
+		name         string
+		measurements Measurements
+		expected     Recommendation
+	}{
+		{
+			name:         "body height",
+			measurements: Measurements{Height: 1.75},
+			expected:     Recommendation{SitHeight: 0.7058, StandHeight: 1.1075, SitMonitorHeight: 1.257, StandMonitorHeight: 1.663},
+		},
+		{
+			// The body height is estimated from the elbow height.
+			name:         "elbow height",
+			measurements: Measurements{ElbowHeight: 1.13},
+			expected:     Recommendation{SitHeight: 0.7073, StandHeight: 1.11, SitMonitorHeight: 1.2598, StandMonitorHeight: 1.6667},
+		},
+		{
+			// The measured elbow height is preferred for standing, the monitor
+			// heights only depend on the body height.
+			name:         "both",
+			measurements: Measurements{Height: 1.75, ElbowHeight: 1.10},
+			expected:     Recommendation{SitHeight: 0.7058, StandHeight: 1.08, SitMonitorHeight: 1.257, StandMonitorHeight: 1.663},
+		},
+	}
+
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) < 0.0001
+	}
+
+	for _, test := range tests {
+		recommendation, err := Recommend(test.measurements)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if !near(recommendation.SitHeight, test.expected.SitHeight) ||
+			!near(recommendation.StandHeight, test.expected.StandHeight) ||
+			!near(recommendation.SitMonitorHeight, test.expected.SitMonitorHeight) ||
+			!near(recommendation.StandMonitorHeight, test.expected.StandMonitorHeight) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, recommendation)
+		}
+	}
+}
+
+func TestRecommendInvalid(t *testing.T) {
+	tests := []struct {
+		name         string
+		measurements Measurements
+		err          error
+	}{
+		{"no measurements", Measurements{}, ErrNoMeasurements},
+		{"negative height", Measurements{Height: -1.75}, nil},
+		{"negative elbow height", Measurements{Height: 1.75, ElbowHeight: -1.1}, nil},
+	}
+
+	for _, test := range tests {
+		_, err := Recommend(test.measurements)
+
+		if err == nil {
+			t.Errorf("%s: expected the measurements to be rejected", test.name)
+		} else if test.err != nil && !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+}
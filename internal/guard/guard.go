@@ -0,0 +1,82 @@
+// Package guard locks the desk against movement, either by the lock command or
+// during the quiet hours defined within the configuration.
+//
+// The configuration is read on every check, so a desk held by a long-running
+// process, e.g. the dashboard, is locked as soon as another process locks it.
+package guard
+
+import (
+	"fmt"
+	"time"
+
+	"idasen-desk/internal/config"
+	"idasen-desk/internal/desk"
+)
+
+// clockFormat is the format of the start and end of quiet hours.
+const clockFormat = "15:04"
+
+// Guard checks whether the desk is locked against movement.
+type Guard struct {
+	path string
+}
+
+// New creates a guard reading the lock state from the configuration at the
+// given path.
+func New(configPath string) *Guard {
+	return &Guard{path: configPath}
+}
+
+// Check returns a *desk.LockedError if the desk is locked, or within quiet
+// hours. Used as the guard of the desk through desk.WithGuard.
+func (g *Guard) Check() error {
+	configuration, err := config.Load(g.path)
+	if err != nil {
+		return fmt.Errorf("failed to load lock state, %w", err)
+	}
+
+	if configuration.Locked {
+		return &desk.LockedError{Reason: "locked, run unlock to allow movement"}
+	}
+
+	now := time.Now()
+
+	for _, window := range configuration.QuietHours {
+		within, err := Within(window, now)
+		if err != nil {
+			return err
+		}
+
+		if within {
+			return &desk.LockedError{Reason: fmt.Sprintf("quiet hours %s-%s", window.Start, window.End)}
+		}
+	}
+
+	return nil
+}
+
+// Within returns true if the time is within the quiet hours window.
+func Within(window config.QuietHours, t time.Time) (bool, error) {
+	start, err := time.Parse(clockFormat, window.Start)
+	if err != nil {
+		return false, fmt.Errorf("invalid start of quiet hours %q, %w", window.Start, err)
+	}
+
+	end, err := time.Parse(clockFormat, window.End)
+	if err != nil {
+		return false, fmt.Errorf("invalid end of quiet hours %q, %w", window.End, err)
+	}
+
+	minutes := func(clock time.Time) int {
+		return clock.Hour()*60 + clock.Minute()
+	}
+
+	at, from, to := minutes(t), minutes(start), minutes(end)
+
+	if from <= to {
+		return at >= from && at < to, nil
+	}
+
+	// The window wraps around midnight, e.g. 22:00 to 07:00.
+	return at >= from || at < to, nil
+}
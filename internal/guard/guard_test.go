@@ -0,0 +1,120 @@
+package guard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"idasen-desk/internal/config"
+	"idasen-desk/internal/desk"
+)
+
+func clock(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	at, err := time.Parse(clockFormat, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return at
+}
+
+func TestWithin(t *testing.T) {
+	daytime := config.QuietHours{Start: "12:00", End: "13:30"}
+	overnight := config.QuietHours{Start: "22:00", End: "07:00"}
+
+	tests := []struct {
+		window   config.QuietHours
+		at       string
+		expected bool
+	}{
+		{daytime, "11:59", false},
+		{daytime, "12:00", true},
+		{daytime, "13:29", true},
+		{daytime, "13:30", false},
+		{overnight, "21:59", false},
+		{overnight, "22:00", true},
+		{overnight, "23:59", true},
+		{overnight, "00:00", true},
+		{overnight, "06:59", true},
+		{overnight, "07:00", false},
+		{overnight, "12:00", false},
+		{config.QuietHours{Start: "09:00", End: "09:00"}, "09:00", false},
+	}
+
+	for _, test := range tests {
+		within, err := Within(test.window, clock(t, test.at))
+		if err != nil {
+			t.Fatalf("%s-%s at %s: %v", test.window.Start, test.window.End, test.at, err)
+		}
+
+		if within != test.expected {
+			t.Errorf("%s-%s at %s: expected %v, got %v",
+				test.window.Start, test.window.End, test.at, test.expected, within)
+		}
+	}
+}
+
+func TestWithinInvalid(t *testing.T) {
+	for _, window := range []config.QuietHours{
+		{Start: "10pm", End: "07:00"},
+		{Start: "22:00", End: ""},
+		{Start: "25:00", End: "07:00"},
+	} {
+		if _, err := Within(window, time.Now()); err == nil {
+			t.Errorf("%q-%q: expected an error", window.Start, window.End)
+		}
+	}
+}
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "desk.yml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Now()
+	around := fmt.Sprintf("quiet_hours:\n  - start: %q\n    end: %q\n",
+		now.Add(-time.Hour).Format(clockFormat), now.Add(time.Hour).Format(clockFormat))
+	elsewhere := fmt.Sprintf("quiet_hours:\n  - start: %q\n    end: %q\n",
+		now.Add(2*time.Hour).Format(clockFormat), now.Add(3*time.Hour).Format(clockFormat))
+
+	tests := []struct {
+		name     string
+		contents string
+		locked   bool
+	}{
+		{"unlocked", "locked: false\n", false},
+		{"locked", "locked: true\n", true},
+		{"quiet hours", around, true},
+		{"outside quiet hours", elsewhere, false},
+	}
+
+	for _, test := range tests {
+		err := New(writeConfig(t, test.contents)).Check()
+
+		var locked *desk.LockedError
+		if errors.As(err, &locked) != test.locked {
+			t.Errorf("%s: expected locked %v, got %v", test.name, test.locked, err)
+		}
+	}
+}
+
+func TestCheckInvalidQuietHours(t *testing.T) {
+	err := New(writeConfig(t, "quiet_hours:\n  - start: noon\n    end: \"13:00\"\n")).Check()
+
+	var locked *desk.LockedError
+	if err == nil || errors.As(err, &locked) {
+		t.Errorf("expected an invalid quiet hours error, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Package history records desk events to disk, one JSON object per line, so
+// they can be inspected later or used to back off automation, e.g. after the
+// user took control of the desk.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"idasen-desk/internal/desk"
+)
+
+// History appends desk events to the file at its path.
+type History struct {
+	path string
+	mu   sync.Mutex
+}
+
+// New creates a history recorded at the given path.
+func New(path string) *History {
+	return &History{path: path}
+}
+
+// Record appends the event to the history, logging rather than returning any
+// error so it can be used directly as a desk event handler.
+func (h *History) Record(event desk.Event) {
+	if err := h.Append(event); err != nil {
+		log.WithError(err).Warn("failed to record desk event")
+	}
+}
+
+// Append appends the event to the history.
+func (h *History) Append(event desk.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event, %w", err)
+	}
+
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history, %w", err)
+	}
+
+	defer file.Close()
+
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history, %w", err)
+	}
+
+	return nil
+}
+
+// Since returns all events recorded at or after the given time, oldest first.
+// Lines that cannot be parsed are skipped.
+func (h *History) Since(since time.Time) ([]desk.Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history, %w", err)
+	}
+
+	defer file.Close()
+
+	var events []desk.Event
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		var event desk.Event
+
+		if err = json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			log.WithError(err).Debug("skipping unreadable history entry")
+			continue
+		}
+
+		if !event.Time.Before(since) {
+			events = append(events, event)
+		}
+	}
+
+	return events, scanner.Err()
+}
+
+// Last returns the most recent event of the given type. The returned bool is
+// false if no such event has been recorded.
+func (h *History) Last(eventType desk.EventType) (desk.Event, bool, error) {
+	events, err := h.Since(time.Time{})
+	if err != nil {
+		return desk.Event{}, false, err
+	}
+
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Type == eventType {
+			return events[i], true, nil
+		}
+	}
+
+	return desk.Event{}, false, nil
+}
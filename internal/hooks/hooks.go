@@ -0,0 +1,189 @@
+// Package hooks runs user configured shell commands on desk events, e.g. to
+// set a chat status to standing once the desk reached the stand height.
+//
+// Each command is passed the event as environment variables and as JSON on
+// stdin, the same JSON recorded in the history.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"idasen-desk/internal/desk"
+)
+
+const (
+	// Timeout is how long a hook can run for before it is killed.
+	Timeout = 30 * time.Second
+
+	// waitDelay is how long to wait for the output of a killed hook, which
+	// processes it started may still hold open.
+	waitDelay = time.Second
+
+	// queueSize is how many events can wait for a single hook before further
+	// events are dropped.
+	queueSize = 64
+)
+
+// Hook is a shell command run for the given events, or for all events when
+// none are given.
+type Hook struct {
+	Events  []desk.EventType
+	Command string
+}
+
+// Runner runs the hooks matching each event it handles, in order for each
+// hook.
+type Runner struct {
+	mu     sync.Mutex
+	closed bool
+	queues []*queue
+	wg     sync.WaitGroup
+}
+
+type queue struct {
+	hook   Hook
+	events chan desk.Event
+}
+
+// New creates a runner for the hooks.
+func New(hooks []Hook) *Runner {
+	r := &Runner{}
+
+	for _, hook := range hooks {
+		q := &queue{hook: hook, events: make(chan desk.Event, queueSize)}
+		r.queues = append(r.queues, q)
+
+		r.wg.Add(1)
+		go r.runQueue(q)
+	}
+
+	return r
+}
+
+// Handle queues the event for every hook matching it, so it can be used
+// directly as a desk event handler without blocking the desk. Each hook runs
+// for one event at a time, in the order the events were emitted.
+func (r *Runner) Handle(event desk.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	for _, q := range r.queues {
+		if !q.hook.matches(event.Type) {
+			continue
+		}
+
+		select {
+		case q.events <- event:
+		default:
+			log.Warnf("hook %q is falling behind, dropping %s", q.hook.Command, event.Type)
+		}
+	}
+}
+
+// Wait stops accepting events and waits for the hooks to run for all queued
+// events, called before exiting so no hook is cut short.
+func (r *Runner) Wait() {
+	r.mu.Lock()
+
+	if !r.closed {
+		r.closed = true
+
+		for _, q := range r.queues {
+			close(q.events)
+		}
+	}
+
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+func (r *Runner) runQueue(q *queue) {
+	defer r.wg.Done()
+
+	for event := range q.events {
+		if err := run(q.hook.Command, event, Timeout); err != nil {
+			log.WithError(err).Warnf("hook %q failed for %s", q.hook.Command, event.Type)
+		}
+	}
+}
+
+func (h Hook) matches(eventType desk.EventType) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+
+	for _, event := range h.Events {
+		if event == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// run runs the command through the shell, passing it the event, killing it
+// once the timeout passes.
+func run(command string, event desk.Event, timeout time.Duration) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event, %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+
+	var output bytes.Buffer
+
+	cmd.Env = append(os.Environ(), environment(event)...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = waitDelay
+
+	log.Debugf("running hook %q for %s", command, event.Type)
+
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output.Bytes()))
+	}
+
+	return nil
+}
+
+// environment returns the event as environment variables. Heights are in
+// meters and the duration in seconds.
+func environment(event desk.Event) []string {
+	format := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	return []string{
+		"DESK_EVENT=" + string(event.Type),
+		"DESK_TIME=" + event.Time.Format(time.RFC3339),
+		"DESK_HEIGHT=" + format(event.Height),
+		"DESK_TARGET=" + format(event.Target),
+		"DESK_DIRECTION=" + event.Direction,
+		"DESK_DURATION=" + format(event.Duration.Seconds()),
+		"DESK_ERROR=" + event.Error,
+		"DESK_PROFILE=" + event.Profile,
+	}
+}
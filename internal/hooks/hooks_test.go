@@ -0,0 +1,160 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"idasen-desk/internal/desk"
+)
+
+var targetReached = desk.Event{
+	Type:     desk.EventTargetReached,
+	Time:     time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC),
+	Height:   1.1,
+	Target:   1.1,
+	Duration: 1500 * time.Millisecond,
+	Profile:  "bob",
+}
+
+// runHooks runs the hooks for the events, waiting for them to complete.
+func runHooks(t *testing.T, hooks []Hook, events ...desk.Event) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are tested with a POSIX shell")
+	}
+
+	runner := New(hooks)
+	for _, event := range events {
+		runner.Handle(event)
+	}
+
+	runner.Wait()
+}
+
+func readOutput(t *testing.T, path string) string {
+	t.Helper()
+
+	output, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+
+	return string(output)
+}
+
+func TestEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events")
+	moveStarted := desk.Event{Type: desk.EventMoveStarted, Time: time.Now()}
+
+	runHooks(t, []Hook{{
+		Events:  []desk.EventType{desk.EventTargetReached},
+		Command: `echo "$DESK_EVENT" >> ` + path,
+	}}, moveStarted, targetReached, moveStarted)
+
+	if output := readOutput(t, path); output != "target_reached\n" {
+		t.Errorf("expected only the subscribed event, got %q", output)
+	}
+}
+
+func TestAllEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events")
+	moveStarted := desk.Event{Type: desk.EventMoveStarted, Time: time.Now()}
+
+	runHooks(t, []Hook{{Command: `echo "$DESK_EVENT" >> ` + path}}, moveStarted, targetReached)
+
+	if output := readOutput(t, path); output != "move_started\ntarget_reached\n" {
+		t.Errorf("expected every event without a filter, got %q", output)
+	}
+}
+
+// TestOrder expects a hook to run for one event at a time, in order, even when
+// an earlier run takes longer.
+func TestOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heights")
+
+	var events []desk.Event
+	for i := 1; i <= 5; i++ {
+		events = append(events, desk.Event{Type: desk.EventManualMove, Height: float64(i)})
+	}
+
+	runHooks(t, []Hook{{
+		Command: `sleep "0.0$((6 - DESK_HEIGHT))"; echo "$DESK_HEIGHT" >> ` + path,
+	}}, events...)
+
+	if output := readOutput(t, path); output != "1\n2\n3\n4\n5\n" {
+		t.Errorf("expected the events in order, got %q", output)
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "environment")
+	runHooks(t, []Hook{{Command: "env > " + path}}, targetReached)
+
+	output := readOutput(t, path)
+
+	for _, variable := range []string{
+		"DESK_EVENT=target_reached",
+		"DESK_TIME=2024-03-04T10:00:00Z",
+		"DESK_HEIGHT=1.1",
+		"DESK_TARGET=1.1",
+		"DESK_DIRECTION=",
+		"DESK_DURATION=1.5",
+		"DESK_ERROR=",
+		"DESK_PROFILE=bob",
+	} {
+		if !strings.Contains(output, variable+"\n") {
+			t.Errorf("expected %s in the environment, got %q", variable, output)
+		}
+	}
+}
+
+func TestStdin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stdin")
+	runHooks(t, []Hook{{Command: "cat > " + path}}, targetReached)
+
+	var event desk.Event
+	if err := json.Unmarshal([]byte(readOutput(t, path)), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	if event != targetReached {
+		t.Errorf("expected the event as JSON, got %+v", event)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are tested with a POSIX shell")
+	}
+
+	// The shell waits for sleep, which keeps the output open once killed.
+	started := time.Now()
+	err := run("sleep 10; true", targetReached, 100*time.Millisecond)
+
+	if err == nil {
+		t.Error("expected the hook to be killed")
+	}
+
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("expected the hook to be killed at the timeout, took %s", elapsed)
+	}
+}
+
+func TestHandleAfterWait(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events")
+
+	runner := New([]Hook{{Command: `echo "$DESK_EVENT" >> ` + path}})
+	runner.Wait()
+	runner.Handle(targetReached)
+	runner.Wait()
+
+	if output := readOutput(t, path); output != "" {
+		t.Errorf("expected no hook to run once waited on, got %q", output)
+	}
+}
@@ -0,0 +1,199 @@
+// Package lock provides an advisory cross-process lock per desk, ensuring only
+// a single process talks to a desk at any given time. The desk only accepts a
+// single bluetooth connection, so two processes would otherwise fight over it.
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+	log "github.com/sirupsen/logrus"
+)
+
+// Mode determines what happens when the desk is already locked by another
+// process.
+type Mode string
+
+const (
+	// ModeWait waits for the other process to release the desk, up to the
+	// timeout.
+	ModeWait Mode = "wait"
+
+	// ModeFail fails immediately with a BusyError.
+	ModeFail Mode = "fail"
+
+	// ModeRoute fails immediately with a BusyError, allowing the caller to
+	// route the request through the process that owns the desk.
+	ModeRoute Mode = "route"
+)
+
+// retryDelay is how often the lock is retried when waiting.
+const retryDelay = 250 * time.Millisecond
+
+var ErrBusy = errors.New("desk busy")
+
+// Owner describes the process holding the lock on the desk.
+type Owner struct {
+	PID     int       `json:"pid"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+// BusyError is returned when the desk is locked by another process.
+type BusyError struct {
+	Owner Owner
+}
+
+func (e *BusyError) Error() string {
+	if e.Owner.PID == 0 {
+		return ErrBusy.Error()
+	}
+
+	return fmt.Sprintf("%s (pid %d, %s)", ErrBusy.Error(), e.Owner.PID, e.Owner.Command)
+}
+
+func (e *BusyError) Is(target error) bool {
+	return target == ErrBusy
+}
+
+// Lock is an acquired lock on a desk.
+type Lock struct {
+	flock   *flock.Flock
+	address string
+}
+
+// Dir returns the per user directory the lock files are stored in. It does not
+// depend on the session, e.g. XDG_RUNTIME_DIR, so a command run from cron and
+// one run over SSH take the same lock.
+func Dir() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("idasen-desk-%d", os.Getuid()))
+}
+
+// ensureDir creates the lock directory only accessible by the user, refusing
+// to use one others can write to as it lives within the shared temporary
+// directory.
+func ensureDir() error {
+	dir := Dir()
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create lock directory, %w", err)
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to create lock directory, %w", err)
+	}
+
+	if !info.IsDir() || info.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("lock directory %s must be a directory only writable by its owner", dir)
+	}
+
+	return nil
+}
+
+// Path returns the path of the lock file for the desk with the given address.
+func Path(address string) string {
+	return filepath.Join(Dir(), fmt.Sprintf("%s.lock", sanitize(address)))
+}
+
+// SocketPath returns the path of the socket the owner of the lock for the desk
+// with the given address listens on for routed requests.
+func SocketPath(address string) string {
+	return filepath.Join(Dir(), fmt.Sprintf("%s.sock", sanitize(address)))
+}
+
+// Acquire locks the desk with the given address for this process. If the desk
+// is locked by another process, ModeWait waits up to the timeout while the
+// other modes fail immediately, both returning a BusyError.
+func Acquire(address string, mode Mode, timeout time.Duration) (*Lock, error) {
+	switch mode {
+	case ModeWait, ModeFail, ModeRoute:
+	default:
+		return nil, fmt.Errorf("unknown lock mode %q, expected wait, fail or route", mode)
+	}
+
+	if err := ensureDir(); err != nil {
+		return nil, err
+	}
+
+	fileLock := flock.New(Path(address))
+
+	locked, err := fileLock.TryLock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock desk, %w", err)
+	}
+
+	if !locked && mode == ModeWait {
+		owner, _ := ReadOwner(address)
+		log.Infof("desk is in use by pid %d (%s), waiting up to %s", owner.PID, owner.Command, timeout)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if locked, err = fileLock.TryLockContext(ctx, retryDelay); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("failed to lock desk, %w", err)
+		}
+	}
+
+	if !locked {
+		owner, _ := ReadOwner(address)
+		return nil, &BusyError{Owner: owner}
+	}
+
+	lock := &Lock{flock: fileLock, address: address}
+
+	if err = lock.writeOwner(); err != nil {
+		return nil, errors.Join(err, lock.Release())
+	}
+
+	return lock, nil
+}
+
+// ReadOwner returns the process that last held the lock on the desk.
+func ReadOwner(address string) (Owner, error) {
+	var owner Owner
+
+	contents, err := os.ReadFile(Path(address))
+	if err != nil {
+		return owner, err
+	}
+
+	err = json.Unmarshal(contents, &owner)
+	return owner, err
+}
+
+// Release unlocks the desk, allowing other processes to acquire it.
+func (l *Lock) Release() error {
+	return l.flock.Unlock()
+}
+
+// writeOwner records this process as the owner of the lock, so others can
+// report who is holding the desk.
+func (l *Lock) writeOwner() error {
+	contents, err := json.Marshal(Owner{
+		PID:     os.Getpid(),
+		Command: strings.Join(os.Args[1:], " "),
+		Started: time.Now(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(l.flock.Path(), contents, 0o600); err != nil {
+		return fmt.Errorf("failed to record lock owner, %w", err)
+	}
+
+	return nil
+}
+
+func sanitize(address string) string {
+	return strings.NewReplacer(":", "", "/", "", "\\", "").Replace(strings.ToLower(address))
+}
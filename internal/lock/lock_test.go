@@ -0,0 +1,191 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAddress = "AA:BB:CC:DD:EE:FF"
+
+func useTempDir(t *testing.T) {
+	t.Helper()
+	t.Setenv("TMPDIR", t.TempDir())
+}
+
+func TestAcquireRelease(t *testing.T) {
+	useTempDir(t)
+
+	lock, err := Acquire(testAddress, ModeFail, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	owner, err := ReadOwner(testAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if owner.PID != os.Getpid() {
+		t.Errorf("expected this process to own the lock, got pid %d", owner.PID)
+	}
+
+	if err = lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := Acquire(testAddress, ModeFail, 0)
+	if err != nil {
+		t.Fatalf("expected the released lock to be acquired again, got %v", err)
+	}
+
+	_ = again.Release()
+}
+
+func TestAcquireBusy(t *testing.T) {
+	for _, mode := range []Mode{ModeFail, ModeRoute} {
+		t.Run(string(mode), func(t *testing.T) {
+			useTempDir(t)
+
+			lock, err := Acquire(testAddress, ModeFail, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer lock.Release()
+
+			started := time.Now()
+			_, err = Acquire(testAddress, mode, time.Minute)
+
+			var busyErr *BusyError
+			if !errors.As(err, &busyErr) || !errors.Is(err, ErrBusy) {
+				t.Fatalf("expected a BusyError, got %v", err)
+			}
+
+			if busyErr.Owner.PID != os.Getpid() {
+				t.Errorf("expected the owner to be reported, got pid %d", busyErr.Owner.PID)
+			}
+
+			if elapsed := time.Since(started); elapsed > time.Second {
+				t.Errorf("expected %s to fail immediately, took %s", mode, elapsed)
+			}
+		})
+	}
+}
+
+func TestAcquireWait(t *testing.T) {
+	useTempDir(t)
+
+	lock, err := Acquire(testAddress, ModeFail, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		_ = lock.Release()
+	}()
+
+	waited, err := Acquire(testAddress, ModeWait, 5*time.Second)
+	if err != nil {
+		t.Fatalf("expected the lock once released, got %v", err)
+	}
+
+	_ = waited.Release()
+}
+
+func TestAcquireWaitTimeout(t *testing.T) {
+	useTempDir(t)
+
+	lock, err := Acquire(testAddress, ModeFail, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer lock.Release()
+
+	if _, err = Acquire(testAddress, ModeWait, 300*time.Millisecond); !errors.Is(err, ErrBusy) {
+		t.Errorf("expected %v after the timeout, got %v", ErrBusy, err)
+	}
+}
+
+func TestAcquireUnknownMode(t *testing.T) {
+	useTempDir(t)
+
+	if _, err := Acquire(testAddress, Mode("steal"), 0); err == nil {
+		t.Error("expected an unknown mode to be rejected")
+	}
+}
+
+func TestPathsPerDesk(t *testing.T) {
+	useTempDir(t)
+
+	path, socket := Path(testAddress), SocketPath(testAddress)
+
+	if !strings.HasSuffix(path, "aabbccddeeff.lock") {
+		t.Errorf("expected the address to be sanitised, got %s", path)
+	}
+
+	if !strings.HasSuffix(socket, "aabbccddeeff.sock") {
+		t.Errorf("expected the address to be sanitised, got %s", socket)
+	}
+
+	if Path("11:22:33:44:55:66") == path {
+		t.Error("expected every desk to have its own lock")
+	}
+}
+
+func TestDirIndependentOfSession(t *testing.T) {
+	useTempDir(t)
+
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	dir := Dir()
+
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	if Dir() != dir {
+		t.Errorf("expected the same directory without a runtime directory, got %s and %s", dir, Dir())
+	}
+
+	if filepath.Dir(Path(testAddress)) != dir || filepath.Dir(SocketPath(testAddress)) != dir {
+		t.Errorf("expected the lock and socket within %s", dir)
+	}
+}
+
+func TestDirPrivate(t *testing.T) {
+	useTempDir(t)
+
+	lock, err := Acquire(testAddress, ModeFail, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer lock.Release()
+
+	info, err := os.Stat(Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Errorf("expected the lock directory to only be accessible by the user, got %o", perm)
+	}
+}
+
+func TestDirWritableByOthers(t *testing.T) {
+	useTempDir(t)
+
+	if err := os.Mkdir(Dir(), 0o777); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chmod(Dir(), 0o777); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Acquire(testAddress, ModeFail, 0); err == nil {
+		t.Error("expected a lock directory writable by others to be refused")
+	}
+}
@@ -0,0 +1,272 @@
+// Package route allows a process to route desk requests through the process
+// currently holding the desk, instead of competing for the single bluetooth
+// connection the desk accepts.
+//
+// The owner of the desk listens on a unix socket. Each connection carries a
+// single newline delimited JSON request and response.
+package route
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"idasen-desk/internal/desk"
+	"idasen-desk/internal/duty"
+)
+
+const (
+	actionHeight = "height"
+	actionMove   = "move"
+	actionStop   = "stop"
+)
+
+// dialTimeout is how long to wait to connect to the owner of the desk.
+const dialTimeout = 2 * time.Second
+
+// Target is the desk requests are routed to. Moves are made on behalf of the
+// requester of the routed request, within its soft limits and attributed to
+// its profile.
+type Target interface {
+	GetHeight() (float64, error)
+	MoveToTargetFor(requester desk.Requester, target float64) error
+	StopMove() error
+}
+
+type request struct {
+	Action    string  `json:"action"`
+	Target    float64 `json:"target,omitempty"`
+	Source    string  `json:"source,omitempty"`
+	Profile   string  `json:"profile,omitempty"`
+	MinHeight float64 `json:"min_height,omitempty"`
+	MaxHeight float64 `json:"max_height,omitempty"`
+}
+
+type response struct {
+	Height float64 `json:"height,omitempty"`
+	Error  string  `json:"error,omitempty"`
+	Kind   string  `json:"kind,omitempty"`
+}
+
+// errorKind is an error preserved across the socket by name.
+type errorKind struct {
+	name string
+	err  error
+}
+
+// errorKinds are the errors preserved across the socket, so a routed request
+// fails with the same error as a direct one. Joined errors take the first
+// kind they match, in the same order the exit code is chosen.
+var errorKinds = []errorKind{
+	{"not_configured", desk.ErrNotConfigured},
+	{"connect_failed", desk.ErrConnectFailed},
+	{"characteristic_missing", desk.ErrCharacteristicMissing},
+	{"out_of_range", desk.ErrOutOfRange},
+	{"safety_stop", desk.ErrSafetyStop},
+	{"manual_override", desk.ErrManualOverride},
+	{"timeout", desk.ErrTimeout},
+	{"locked", desk.ErrLocked},
+	{"duty_cycle", duty.ErrExceeded},
+	{"move_stopped", desk.ErrMoveStopped},
+	{"move_preempted", desk.ErrMovePreempted},
+}
+
+// kindOf returns the name of the first kind the error matches, empty if none.
+func kindOf(err error) string {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind.name
+		}
+	}
+
+	return ""
+}
+
+// errorOfKind returns the error of the kind with the name, nil if unknown.
+func errorOfKind(name string) error {
+	for _, kind := range errorKinds {
+		if kind.name == name {
+			return kind.err
+		}
+	}
+
+	return nil
+}
+
+// routedError is an error returned by the owner of the desk, matching the
+// desk error it was caused by with errors.Is.
+type routedError struct {
+	msg  string
+	kind error
+}
+
+func (e *routedError) Error() string {
+	return e.msg
+}
+
+func (e *routedError) Unwrap() error {
+	return e.kind
+}
+
+// Server accepts routed requests and executes them against the target.
+type Server struct {
+	listener net.Listener
+	target   Target
+}
+
+// Serve starts listening for routed requests on the socket at the given path,
+// executing them against the target. Any stale socket left behind by a
+// previous owner is replaced.
+func Serve(path string, target Target) (*Server, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket, %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for routed requests, %w", err)
+	}
+
+	server := &Server{listener: listener, target: target}
+	go server.accept()
+
+	return server, nil
+}
+
+// Close stops accepting routed requests.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.WithError(err).Warn("failed to accept routed request")
+			}
+
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		log.WithError(err).Warn("failed to decode routed request")
+		return
+	}
+
+	log.Infof("handling routed %s request from %s", req.Action, req.Source)
+
+	var resp response
+	var err error
+
+	switch req.Action {
+	case actionHeight:
+		resp.Height, err = s.target.GetHeight()
+	case actionMove:
+		err = s.target.MoveToTargetFor(desk.Requester{
+			Source:    req.Source,
+			Profile:   req.Profile,
+			MinHeight: req.MinHeight,
+			MaxHeight: req.MaxHeight,
+		}, req.Target)
+	case actionStop:
+		err = s.target.StopMove()
+	default:
+		err = fmt.Errorf("unknown routed action %q", req.Action)
+	}
+
+	if err != nil {
+		resp.Error = err.Error()
+		resp.Kind = kindOf(err)
+	}
+
+	if err = json.NewEncoder(conn).Encode(resp); err != nil {
+		log.WithError(err).Warn("failed to write routed response")
+	}
+}
+
+// Client routes requests to the process owning the desk.
+type Client struct {
+	path      string
+	name      string
+	requester desk.Requester
+}
+
+// NewClient creates a client routing requests through the socket at the given
+// path. The name is used to describe the routed desk and the requester to
+// describe the client to the owning process, which moves the desk within its
+// soft limits and attributes the moves to its profile.
+func NewClient(path, name string, requester desk.Requester) *Client {
+	return &Client{path: path, name: name, requester: requester}
+}
+
+// Name returns the description of the routed desk.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// GetHeight returns the current height of the desk from the owning process.
+func (c *Client) GetHeight() (float64, error) {
+	resp, err := c.send(request{Action: actionHeight})
+	return resp.Height, err
+}
+
+// MoveToTarget asks the owning process to move the desk to the target,
+// blocking until the move has completed.
+func (c *Client) MoveToTarget(target float64) error {
+	_, err := c.send(request{Action: actionMove, Target: target})
+	return err
+}
+
+// StopMove asks the owning process to stop the desk, cancelling its move.
+func (c *Client) StopMove() error {
+	_, err := c.send(request{Action: actionStop})
+	return err
+}
+
+// Close does nothing, each request uses its own connection.
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) send(req request) (response, error) {
+	var resp response
+
+	conn, err := net.DialTimeout("unix", c.path, dialTimeout)
+	if err != nil {
+		return resp, fmt.Errorf("failed to connect to desk owner, %w", err)
+	}
+
+	defer conn.Close()
+
+	req.Source = c.requester.Source
+	req.Profile = c.requester.Profile
+	req.MinHeight, req.MaxHeight = c.requester.MinHeight, c.requester.MaxHeight
+
+	if err = json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, fmt.Errorf("failed to send routed request, %w", err)
+	}
+
+	if err = json.NewDecoder(conn).Decode(&resp); err != nil {
+		return resp, fmt.Errorf("failed to read routed response, %w", err)
+	}
+
+	if resp.Error != "" {
+		return resp, &routedError{msg: resp.Error, kind: errorOfKind(resp.Kind)}
+	}
+
+	return resp, nil
+}
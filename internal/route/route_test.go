@@ -0,0 +1,170 @@
+package route
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"idasen-desk/internal/desk"
+	"idasen-desk/internal/duty"
+)
+
+// fakeTarget records the routed requests, failing them with err.
+type fakeTarget struct {
+	height    float64
+	requester desk.Requester
+	target    float64
+	stopped   bool
+	err       error
+}
+
+func (f *fakeTarget) GetHeight() (float64, error) {
+	return f.height, f.err
+}
+
+func (f *fakeTarget) MoveToTargetFor(requester desk.Requester, target float64) error {
+	f.requester, f.target = requester, target
+	return f.err
+}
+
+func (f *fakeTarget) StopMove() error {
+	f.stopped = true
+	return f.err
+}
+
+// requester is the profile routing the requests, with its own soft limits.
+var requester = desk.Requester{Source: "test", Profile: "bob", MinHeight: 0.8, MaxHeight: 1.2}
+
+func serve(t *testing.T, target Target) *Client {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "desk.sock")
+
+	server, err := Serve(path, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+
+	return NewClient(path, "desk (via pid 1)", requester)
+}
+
+func TestRouteRequests(t *testing.T) {
+	target := &fakeTarget{height: 0.74}
+	client := serve(t, target)
+
+	height, err := client.GetHeight()
+	if err != nil || height != 0.74 {
+		t.Fatalf("expected height 0.74, got %.2f (%v)", height, err)
+	}
+
+	if err = client.MoveToTarget(1.12); err != nil || target.target != 1.12 {
+		t.Fatalf("expected a move to 1.12, got %.2f (%v)", target.target, err)
+	}
+
+	if target.requester != requester {
+		t.Errorf("expected the move to be made for the client's profile, got %+v", target.requester)
+	}
+
+	if err = client.StopMove(); err != nil || !target.stopped {
+		t.Fatalf("expected the desk to be stopped, got %v", err)
+	}
+}
+
+func TestRouteErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"out of range", &desk.RangeError{Name: "target", Height: 2, Min: 0.62, Max: 1.27}, desk.ErrOutOfRange},
+		{"locked", &desk.LockedError{Reason: "quiet hours 22:00-07:00"}, desk.ErrLocked},
+		{"safety stop", desk.ErrSafetyStop, desk.ErrSafetyStop},
+		{"manual override", desk.ErrManualOverride, desk.ErrManualOverride},
+		{"stopped", desk.ErrMoveStopped, desk.ErrMoveStopped},
+		{"preempted", desk.ErrMovePreempted, desk.ErrMovePreempted},
+		{"duty cycle", &duty.ExceededError{Budget: duty.Budget{Available: time.Now()}}, duty.ErrExceeded},
+		{"wrapped", errors.Join(errors.New("while moving"), desk.ErrTimeout), desk.ErrTimeout},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := serve(t, &fakeTarget{err: test.err})
+
+			err := client.MoveToTarget(1.0)
+
+			if !errors.Is(err, test.kind) {
+				t.Errorf("expected the error to match %v, got %v", test.kind, err)
+			}
+
+			if err == nil || err.Error() != test.err.Error() {
+				t.Errorf("expected the message %q, got %v", test.err.Error(), err)
+			}
+		})
+	}
+}
+
+func TestRouteUnknownError(t *testing.T) {
+	client := serve(t, &fakeTarget{err: errors.New("bluetooth adapter went away")})
+
+	err := client.MoveToTarget(1.0)
+	if err == nil {
+		t.Fatal("expected the error to be returned")
+	}
+
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			t.Errorf("expected an unknown error not to match %v", kind.err)
+		}
+	}
+}
+
+// TestRouteJoinedErrors expects joined errors to keep the kind with the
+// highest precedence, whatever order they were joined in.
+func TestRouteJoinedErrors(t *testing.T) {
+	locked := &desk.LockedError{Reason: "locked"}
+	exceeded := &duty.ExceededError{Budget: duty.Budget{Available: time.Now()}}
+	stopFailed := errors.New("failed to stop")
+
+	tests := []struct {
+		name     string
+		err      error
+		kind     error
+		excluded error
+	}{
+		{"safety stop and stop failure", errors.Join(desk.ErrSafetyStop, stopFailed), desk.ErrSafetyStop, nil},
+		{"locked before duty cycle", errors.Join(exceeded, locked), desk.ErrLocked, duty.ErrExceeded},
+		{"timeout before stopped", errors.Join(desk.ErrMoveStopped, desk.ErrTimeout), desk.ErrTimeout, desk.ErrMoveStopped},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := serve(t, &fakeTarget{err: test.err})
+
+			// Map iteration would pick a different kind between runs.
+			for i := 0; i < 10; i++ {
+				err := client.MoveToTarget(1.0)
+
+				if !errors.Is(err, test.kind) {
+					t.Fatalf("expected the error to match %v, got %v", test.kind, err)
+				}
+
+				if test.excluded != nil && errors.Is(err, test.excluded) {
+					t.Fatalf("expected the error not to match %v", test.excluded)
+				}
+			}
+		})
+	}
+}
+
+func TestRouteWithoutOwner(t *testing.T) {
+	client := NewClient(filepath.Join(t.TempDir(), "missing.sock"), "desk", requester)
+
+	if _, err := client.GetHeight(); err == nil {
+		t.Error("expected routing without an owner to fail")
+	}
+}
@@ -0,0 +1,333 @@
+// Package rules moves the desk when declarative conditions are met, e.g. to
+// stand after sitting for 50 minutes during working hours.
+//
+// How long the desk has been sitting or standing is tracked from the live
+// height stream, seeded from the recent history so it survives restarts. Rules
+// are evaluated in order of priority and only the first matching rule fires,
+// with every evaluation explaining why each rule fired or did not.
+package rules
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"idasen-desk/internal/config"
+	"idasen-desk/internal/desk"
+	"idasen-desk/internal/guard"
+)
+
+// Position is whether the desk is at a sitting or standing height.
+type Position string
+
+const (
+	Sitting  Position = "sitting"
+	Standing Position = "standing"
+)
+
+// Rule moves the desk to the target once the desk has been in the position
+// for the duration, optionally limited to a daily window and days of the week.
+type Rule struct {
+	Name     string
+	Priority int
+
+	Position Position
+	For      time.Duration
+
+	// Start and End are the daily window in the 24-hour format 15:04 the rule
+	// applies within, both empty to apply all day.
+	Start string
+	End   string
+
+	// Days are the days of the week the rule applies on, empty for every day.
+	Days []time.Weekday
+
+	Target   float64
+	Cooldown time.Duration
+}
+
+// Mover is the desk the rules move.
+type Mover interface {
+	MoveToTarget(target float64) error
+	MoveLimits() (minHeight, maxHeight float64)
+}
+
+// Decision explains the outcome of evaluating a rule.
+type Decision struct {
+	Rule   string `json:"rule"`
+	Fire   bool   `json:"fire"`
+	Reason string `json:"reason"`
+}
+
+// Engine evaluates the rules against the position of the desk.
+type Engine struct {
+	rules     []Rule
+	threshold float64
+	mover     Mover
+	guard     func(distance float64) error
+
+	mu       sync.Mutex
+	height   float64
+	position Position
+	since    time.Time
+	fired    map[string]time.Time
+}
+
+// New creates an engine for the rules, treating heights at or above the
+// threshold as standing. The guard is checked before a rule fires with the
+// distance in meters the desk would move, nil when the desk is never refused.
+func New(rules []Rule, threshold float64, mover Mover, guard func(distance float64) error) *Engine {
+	sorted := append([]Rule{}, rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+
+	return &Engine{
+		rules:     sorted,
+		threshold: threshold,
+		mover:     mover,
+		guard:     guard,
+		fired:     map[string]time.Time{},
+	}
+}
+
+// PositionOf returns the position of the height.
+func (e *Engine) PositionOf(height float64) Position {
+	if height >= e.threshold {
+		return Standing
+	}
+
+	return Sitting
+}
+
+// Lookback returns how far back the history is needed to seed the engine, the
+// longest time any rule waits for the desk to be in a position.
+func (e *Engine) Lookback() time.Duration {
+	var lookback time.Duration
+
+	for _, rule := range e.rules {
+		lookback = max(lookback, rule.For)
+	}
+
+	return lookback
+}
+
+// Seed sets the position from the current height, taking since when the desk
+// has been in it from the recorded events, oldest first. The desk is assumed
+// to have been in the position since the newest event it came to rest with,
+// if that shows the position, otherwise since now. Events such as connecting
+// to the desk show the height without it having moved, so are ignored.
+func (e *Engine) Seed(height float64, events []desk.Event, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.height, e.position, e.since = height, e.PositionOf(height), now
+
+	for i := len(events) - 1; i >= 0; i-- {
+		if !restedWith(events[i]) {
+			continue
+		}
+
+		if e.PositionOf(events[i].Height) == e.position {
+			e.since = events[i].Time
+		}
+
+		return
+	}
+}
+
+// restedWith returns true if the desk came to rest at the height of the event.
+func restedWith(event desk.Event) bool {
+	switch event.Type {
+	case desk.EventTargetReached, desk.EventMotorStopped, desk.EventManualMove:
+		return event.Height != 0
+	}
+
+	return false
+}
+
+// Observe updates the position from a height reported at the given time.
+func (e *Engine) Observe(height float64, at time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.height = height
+
+	if position := e.PositionOf(height); position != e.position {
+		e.position, e.since = position, at
+	}
+}
+
+// Position returns the position of the desk and since when it has been in it.
+func (e *Engine) Position() (Position, time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.position, e.since
+}
+
+// Evaluate explains which rule fires at the given time and why every other
+// rule does not, in order of priority, without moving the desk.
+func (e *Engine) Evaluate(now time.Time) []Decision {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.evaluateLocked(now)
+}
+
+func (e *Engine) evaluateLocked(now time.Time) []Decision {
+	decisions := make([]Decision, 0, len(e.rules))
+	var firing string
+
+	for _, rule := range e.rules {
+		decision := Decision{Rule: rule.Name}
+
+		switch reason := e.skipReason(rule, now); {
+		case reason != "":
+			decision.Reason = reason
+		case firing != "":
+			decision.Reason = fmt.Sprintf("matched, but rule %s has priority", firing)
+		default:
+			decision.Reason = e.fireReason(rule, now)
+
+			if err := e.checkGuard(math.Abs(rule.Target - e.height)); err != nil {
+				decision.Reason = fmt.Sprintf("matched, but %s", err)
+			} else {
+				decision.Fire = true
+			}
+
+			firing = rule.Name
+		}
+
+		decisions = append(decisions, decision)
+	}
+
+	return decisions
+}
+
+// Run evaluates the rules at the given time and moves the desk for the rule
+// that fires, if any. The cooldown of the rule starts even if the move fails,
+// so a desk taken over by the user is not fought over.
+func (e *Engine) Run(now time.Time) ([]Decision, error) {
+	e.mu.Lock()
+	decisions := e.evaluateLocked(now)
+
+	var rule *Rule
+	for i, decision := range decisions {
+		if decision.Fire {
+			rule = &e.rules[i]
+			e.fired[rule.Name] = now
+		}
+	}
+
+	e.mu.Unlock()
+
+	if rule == nil {
+		return decisions, nil
+	}
+
+	if err := e.mover.MoveToTarget(rule.Target); err != nil {
+		return decisions, fmt.Errorf("failed to move for rule %s, %w", rule.Name, err)
+	}
+
+	return decisions, nil
+}
+
+// skipReason returns why the rule does not match, empty if it does.
+func (e *Engine) skipReason(rule Rule, now time.Time) string {
+	if len(rule.Days) > 0 && !containsDay(rule.Days, now.Weekday()) {
+		return fmt.Sprintf("not on %s", now.Weekday())
+	}
+
+	if rule.Start != "" || rule.End != "" {
+		window := config.QuietHours{Start: rule.Start, End: rule.End}
+
+		within, err := guard.Within(window, now)
+		if err != nil {
+			return err.Error()
+		}
+
+		if !within {
+			return fmt.Sprintf("outside %s-%s", rule.Start, rule.End)
+		}
+	}
+
+	if e.position != rule.Position {
+		return fmt.Sprintf("desk is %s, not %s", e.position, rule.Position)
+	}
+
+	if elapsed := now.Sub(e.since); elapsed < rule.For {
+		return fmt.Sprintf("%s for %s of %s", e.position, elapsed.Round(time.Second), rule.For)
+	}
+
+	if fired, ok := e.fired[rule.Name]; ok && now.Sub(fired) < rule.Cooldown {
+		return fmt.Sprintf("cooling down until %s", fired.Add(rule.Cooldown).Format("15:04:05"))
+	}
+
+	if minHeight, maxHeight := e.mover.MoveLimits(); rule.Target < minHeight || rule.Target > maxHeight {
+		return fmt.Sprintf("target %.4f is outside the limits %.4f-%.4f", rule.Target, minHeight, maxHeight)
+	}
+
+	return ""
+}
+
+// fireReason describes why a matching rule fires.
+func (e *Engine) fireReason(rule Rule, now time.Time) string {
+	return fmt.Sprintf("%s for %s, moving to %.4f", e.position, now.Sub(e.since).Round(time.Second), rule.Target)
+}
+
+func (e *Engine) checkGuard(distance float64) error {
+	if e.guard == nil {
+		return nil
+	}
+
+	return e.guard(distance)
+}
+
+// ParseDays parses days of the week, e.g. "mon" or "monday", as well as
+// "weekdays" and "weekends".
+func ParseDays(names []string) ([]time.Weekday, error) {
+	var days []time.Weekday
+
+	for _, name := range names {
+		switch name = strings.ToLower(name); name {
+		case "weekdays":
+			days = append(days, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+			continue
+		case "weekends":
+			days = append(days, time.Saturday, time.Sunday)
+			continue
+		}
+
+		day, ok := parseDay(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", name)
+		}
+
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+func parseDay(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, true
+		}
+	}
+
+	return 0, false
+}
+
+func containsDay(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+
+	return false
+}
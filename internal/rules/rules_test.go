@@ -0,0 +1,271 @@
+package rules
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"idasen-desk/internal/desk"
+)
+
+// Monday at 10:00.
+var now = time.Date(2024, 3, 4, 10, 0, 0, 0, time.Local)
+
+type fakeMover struct {
+	targets []float64
+	err     error
+}
+
+func (m *fakeMover) MoveToTarget(target float64) error {
+	m.targets = append(m.targets, target)
+	return m.err
+}
+
+func (m *fakeMover) MoveLimits() (float64, float64) {
+	return 0.70, 1.20
+}
+
+func newEngine(mover Mover, guard func(float64) error, rules ...Rule) *Engine {
+	return New(rules, 0.9, mover, guard)
+}
+
+func at(height float64, before time.Duration) desk.Event {
+	return desk.Event{Type: desk.EventTargetReached, Time: now.Add(-before), Height: height}
+}
+
+func TestSeed(t *testing.T) {
+	connected := desk.Event{Type: desk.EventConnected, Time: now.Add(-time.Minute), Height: 1.1}
+	started := desk.Event{Type: desk.EventMoveStarted, Time: now.Add(-5 * time.Minute), Height: 0.75}
+
+	tests := []struct {
+		name     string
+		height   float64
+		events   []desk.Event
+		position Position
+		since    time.Time
+	}{
+		{"empty history", 1.1, nil, Standing, now},
+		{"silent history", 1.1, []desk.Event{connected}, Standing, now},
+		{
+			name:     "newest event at rest",
+			height:   1.1,
+			events:   []desk.Event{at(0.75, 50*time.Minute), at(1.1, 30*time.Minute), at(1.15, 20*time.Minute)},
+			position: Standing,
+			since:    now.Add(-20 * time.Minute),
+		},
+		{
+			name:     "events not at rest ignored",
+			height:   1.1,
+			events:   []desk.Event{at(1.1, 30*time.Minute), started, connected},
+			position: Standing,
+			since:    now.Add(-30 * time.Minute),
+		},
+		{"moved since the last event", 0.75, []desk.Event{at(1.1, 20*time.Minute)}, Sitting, now},
+	}
+
+	for _, test := range tests {
+		engine := newEngine(&fakeMover{}, nil)
+		engine.Seed(test.height, test.events, now)
+
+		if position, since := engine.Position(); position != test.position || !since.Equal(test.since) {
+			t.Errorf("%s: expected %s since %s, got %s since %s", test.name,
+				test.position, test.since.Format(time.TimeOnly), position, since.Format(time.TimeOnly))
+		}
+	}
+}
+
+// TestSeedFiresAfterFor expects a rule not to fire straight away when nothing
+// shows since when the desk has been in the position.
+func TestSeedFiresAfterFor(t *testing.T) {
+	mover := &fakeMover{}
+	engine := newEngine(mover, nil, Rule{Name: "stand", Position: Sitting, For: 50 * time.Minute, Target: 1.1})
+	engine.Seed(0.75, []desk.Event{{Type: desk.EventConnected, Time: now, Height: 0.75}}, now)
+
+	for _, after := range []time.Duration{0, 49 * time.Minute, 50 * time.Minute} {
+		if _, err := engine.Run(now.Add(after)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(mover.targets) != 1 {
+		t.Errorf("expected the rule to fire once sitting for 50m, got %v", mover.targets)
+	}
+}
+
+func TestLookback(t *testing.T) {
+	engine := newEngine(&fakeMover{}, nil,
+		Rule{Name: "a", For: 30 * time.Minute},
+		Rule{Name: "b", For: 50 * time.Minute},
+		Rule{Name: "c"},
+	)
+
+	if lookback := engine.Lookback(); lookback != 50*time.Minute {
+		t.Errorf("expected the longest For, got %s", lookback)
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	stand := Rule{Name: "stand", Position: Sitting, For: 50 * time.Minute, Target: 1.1, Cooldown: time.Hour}
+
+	tests := []struct {
+		name   string
+		change func(rule *Rule, e *Engine)
+		reason string
+	}{
+		{"matches", func(*Rule, *Engine) {}, ""},
+		{"day before everything", func(r *Rule, e *Engine) {
+			r.Days = []time.Weekday{time.Sunday}
+			r.Start, r.End = "12:00", "13:00"
+			e.position = Standing
+		}, "not on Monday"},
+		{"window before position", func(r *Rule, e *Engine) {
+			r.Start, r.End = "12:00", "13:00"
+			e.position = Standing
+		}, "outside 12:00-13:00"},
+		{"invalid window", func(r *Rule, _ *Engine) { r.Start, r.End = "noon", "13:00" }, "invalid start"},
+		{"position before duration", func(_ *Rule, e *Engine) {
+			e.position, e.since = Standing, now
+		}, "desk is standing, not sitting"},
+		{"duration before cooldown", func(r *Rule, e *Engine) {
+			e.since = now.Add(-10 * time.Minute)
+			e.fired[r.Name] = now
+		}, "sitting for 10m0s of 50m0s"},
+		{"cooldown before limits", func(r *Rule, e *Engine) {
+			r.Target = 1.5
+			e.fired[r.Name] = now.Add(-30 * time.Minute)
+		}, "cooling down until 10:30:00"},
+		{"cooldown passed", func(r *Rule, e *Engine) { e.fired[r.Name] = now.Add(-time.Hour) }, ""},
+		{"limits", func(r *Rule, _ *Engine) { r.Target = 1.5 }, "target 1.5000 is outside the limits 0.7000-1.2000"},
+	}
+
+	for _, test := range tests {
+		engine := newEngine(&fakeMover{}, nil)
+		engine.Seed(0.75, nil, now)
+		engine.since = now.Add(-time.Hour)
+
+		rule := stand
+		test.change(&rule, engine)
+
+		reason := engine.skipReason(rule, now)
+		if (test.reason == "") != (reason == "") || !strings.HasPrefix(reason, test.reason) {
+			t.Errorf("%s: expected %q, got %q", test.name, test.reason, reason)
+		}
+	}
+}
+
+func TestPriority(t *testing.T) {
+	engine := newEngine(&fakeMover{}, nil,
+		Rule{Name: "low", Position: Sitting, Target: 1.0},
+		Rule{Name: "high", Priority: 10, Position: Sitting, Target: 1.1},
+		Rule{Name: "standing", Priority: 20, Position: Standing, Target: 0.75},
+		Rule{Name: "also low", Position: Sitting, Target: 1.05},
+	)
+
+	engine.Seed(0.75, nil, now)
+	decisions := engine.Evaluate(now)
+
+	expected := []struct {
+		rule string
+		fire bool
+	}{{"standing", false}, {"high", true}, {"low", false}, {"also low", false}}
+
+	for i, decision := range decisions {
+		if decision.Rule != expected[i].rule || decision.Fire != expected[i].fire {
+			t.Fatalf("expected %v, got %+v", expected, decisions)
+		}
+	}
+
+	if decisions[2].Reason != "matched, but rule high has priority" {
+		t.Errorf("expected the lower priority rule to be explained, got %q", decisions[2].Reason)
+	}
+}
+
+func TestRunCooldown(t *testing.T) {
+	mover := &fakeMover{}
+	engine := newEngine(mover, nil, Rule{Name: "stand", Position: Sitting, Target: 1.1, Cooldown: time.Hour})
+	engine.Seed(0.75, nil, now)
+
+	for _, after := range []time.Duration{0, 30 * time.Minute, time.Hour} {
+		if _, err := engine.Run(now.Add(after)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(mover.targets) != 2 {
+		t.Errorf("expected the rule to fire again once cooled down, got %v", mover.targets)
+	}
+}
+
+func TestRunCooldownAfterFailedMove(t *testing.T) {
+	mover := &fakeMover{err: desk.ErrManualOverride}
+	engine := newEngine(mover, nil, Rule{Name: "stand", Position: Sitting, Target: 1.1, Cooldown: time.Hour})
+	engine.Seed(0.75, nil, now)
+
+	if _, err := engine.Run(now); !errors.Is(err, desk.ErrManualOverride) {
+		t.Fatalf("expected the move error, got %v", err)
+	}
+
+	if _, err := engine.Run(now.Add(time.Minute)); err != nil || len(mover.targets) != 1 {
+		t.Errorf("expected the cooldown to start after a failed move, got %v (%v)", mover.targets, err)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	mover := &fakeMover{}
+	refused := errors.New("motor duty cycle exceeded")
+
+	var distances []float64
+	engine := newEngine(mover, func(distance float64) error {
+		distances = append(distances, distance)
+		return refused
+	}, Rule{Name: "stand", Position: Sitting, Target: 1.1, Cooldown: time.Hour})
+
+	engine.Seed(0.75, nil, now)
+
+	decisions, err := engine.Run(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decisions[0].Fire || decisions[0].Reason != "matched, but motor duty cycle exceeded" {
+		t.Errorf("expected the refused rule not to fire, got %+v", decisions[0])
+	}
+
+	if len(distances) != 1 || math.Abs(distances[0]-0.35) > 0.0001 {
+		t.Errorf("expected the guard to be given the distance of the move, got %v", distances)
+	}
+
+	if len(mover.targets) != 0 {
+		t.Errorf("expected the desk not to move, got %v", mover.targets)
+	}
+
+	// The cooldown only starts once the rule fires.
+	engine.guard = nil
+	if _, err = engine.Run(now.Add(time.Minute)); err != nil || len(mover.targets) != 1 {
+		t.Errorf("expected the rule to fire once allowed, got %v (%v)", mover.targets, err)
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	days, err := ParseDays([]string{"weekends", "Mon", "friday"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []time.Weekday{time.Saturday, time.Sunday, time.Monday, time.Friday}
+	if len(days) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, days)
+	}
+
+	for i := range expected {
+		if days[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, days)
+		}
+	}
+
+	if _, err = ParseDays([]string{"someday"}); err == nil {
+		t.Error("expected an invalid day to fail")
+	}
+}
@@ -0,0 +1,315 @@
+// Package webhook posts desk events to HTTP endpoints, e.g. a team chat bot.
+//
+// Every event is posted as the same JSON recorded in the history. When a
+// secret is configured the body is signed with HMAC-SHA256, sent hex encoded
+// within the X-Desk-Signature header as "sha256=<signature>". Failed
+// deliveries are retried with an exponential backoff and appended to the dead
+// letter log once all attempts failed.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"idasen-desk/internal/desk"
+)
+
+const (
+	// MaxAttempts is how many times an event is posted before it is dead
+	// lettered.
+	MaxAttempts = 5
+
+	// InitialBackoff is how long to wait before the first retry, doubling
+	// with every further retry.
+	InitialBackoff = time.Second
+
+	// requestTimeout is how long a single delivery attempt can take.
+	requestTimeout = 10 * time.Second
+
+	// queueSize is how many events can wait for delivery to a single webhook
+	// before further events are dead lettered.
+	queueSize = 64
+)
+
+// errClosed is the reason events are dead lettered when the sender is closed
+// before they could be delivered.
+var errClosed = errors.New("webhook sender closed before the event was delivered")
+
+// Webhook is an endpoint events are posted to, for the given events or all
+// events when none are given. Deliveries are signed when the secret is set.
+type Webhook struct {
+	URL    string
+	Events []desk.EventType
+	Secret string
+}
+
+// DeadLetter is an event that could not be delivered, appended to the dead
+// letter log as a JSON line.
+type DeadLetter struct {
+	Time     time.Time  `json:"time"`
+	URL      string     `json:"url"`
+	Event    desk.Event `json:"event"`
+	Attempts int        `json:"attempts"`
+	Error    string     `json:"error"`
+}
+
+// Sender delivers events to the webhooks, in order for each webhook.
+type Sender struct {
+	client         *http.Client
+	deadLetterPath string
+	backoff        time.Duration
+
+	// ctx is cancelled once Close stops waiting for deliveries.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	closed bool
+	queues []*queue
+	wg     sync.WaitGroup
+}
+
+type queue struct {
+	webhook Webhook
+	events  chan desk.Event
+}
+
+// New creates a sender for the webhooks, appending undeliverable events to
+// the dead letter log at the given path. An empty path drops them instead.
+func New(webhooks []Webhook, deadLetterPath string) *Sender {
+	return newSender(webhooks, deadLetterPath, InitialBackoff)
+}
+
+func newSender(webhooks []Webhook, deadLetterPath string, backoff time.Duration) *Sender {
+	s := &Sender{
+		client:         &http.Client{Timeout: requestTimeout},
+		deadLetterPath: deadLetterPath,
+		backoff:        backoff,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	for _, webhook := range webhooks {
+		q := &queue{webhook: webhook, events: make(chan desk.Event, queueSize)}
+		s.queues = append(s.queues, q)
+
+		s.wg.Add(1)
+		go s.deliverQueue(q)
+	}
+
+	return s
+}
+
+// Handle queues the event for delivery to every webhook subscribed to it, so
+// it can be used directly as a desk event handler without blocking the desk.
+func (s *Sender) Handle(event desk.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	for _, q := range s.queues {
+		if !q.webhook.matches(event.Type) {
+			continue
+		}
+
+		select {
+		case q.events <- event:
+		default:
+			s.deadLetter(q.webhook, event, 0, fmt.Errorf("delivery queue is full"))
+		}
+	}
+}
+
+// Close stops accepting events and waits for all queued events to be
+// delivered or dead lettered, called before exiting so no event is lost. Once
+// the context is done the deliveries in flight are abandoned, and they and the
+// events still queued are dead lettered instead.
+func (s *Sender) Close(ctx context.Context) {
+	s.mu.Lock()
+
+	if !s.closed {
+		s.closed = true
+
+		for _, q := range s.queues {
+			close(q.events)
+		}
+	}
+
+	s.mu.Unlock()
+
+	defer s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	log.Warn("stopped waiting for webhooks, dead lettering the undelivered events")
+	s.cancel()
+	<-done
+}
+
+func (w Webhook) matches(eventType desk.EventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Sender) deliverQueue(q *queue) {
+	defer s.wg.Done()
+
+	for event := range q.events {
+		if s.ctx.Err() != nil {
+			s.deadLetter(q.webhook, event, 0, errClosed)
+			continue
+		}
+
+		attempts, err := s.deliver(q.webhook, event)
+		if err != nil {
+			log.WithError(err).Warnf("failed to deliver %s to webhook %s", event.Type, q.webhook.URL)
+			s.deadLetter(q.webhook, event, attempts, err)
+		}
+	}
+}
+
+// deliver posts the event to the webhook, retrying with an exponential
+// backoff. Returns the number of attempts made.
+func (s *Sender) deliver(webhook Webhook, event desk.Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal event, %w", err)
+	}
+
+	backoff := s.backoff
+
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(webhook, event, body)
+		if err == nil {
+			return attempt, nil
+		}
+
+		if s.ctx.Err() != nil {
+			return attempt, fmt.Errorf("%w, %w", errClosed, err)
+		}
+
+		if !retry || attempt >= MaxAttempts {
+			return attempt, err
+		}
+
+		log.WithError(err).Debugf("retrying webhook %s in %s", webhook.URL, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+			return attempt, fmt.Errorf("%w, %w", errClosed, err)
+		}
+
+		backoff *= 2
+	}
+}
+
+// post makes a single delivery attempt, returning whether a failure is worth
+// retrying. Client errors other than rate limiting are not.
+func (s *Sender) post(webhook Webhook, event desk.Event, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request, %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Desk-Event", string(event.Type))
+
+	if webhook.Secret != "" {
+		req.Header.Set("X-Desk-Signature", "sha256="+Sign(webhook.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to post event, %w", err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook responded with %s", resp.Status)
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of the body, allowing a
+// receiver to verify the X-Desk-Signature header.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// deadLetter appends the undelivered event to the dead letter log.
+func (s *Sender) deadLetter(webhook Webhook, event desk.Event, attempts int, cause error) {
+	if s.deadLetterPath == "" {
+		return
+	}
+
+	line, err := json.Marshal(DeadLetter{
+		Time:     time.Now(),
+		URL:      webhook.URL,
+		Event:    event,
+		Attempts: attempts,
+		Error:    cause.Error(),
+	})
+
+	if err == nil {
+		err = appendLine(s.deadLetterPath, line)
+	}
+
+	if err != nil {
+		log.WithError(err).Warn("failed to dead letter webhook event")
+	}
+}
+
+func appendLine(path string, line []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter log, %w", err)
+	}
+
+	defer file.Close()
+
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write dead letter log, %w", err)
+	}
+
+	return nil
+}
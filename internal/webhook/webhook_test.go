@@ -0,0 +1,238 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"idasen-desk/internal/desk"
+)
+
+// receiver is a webhook endpoint responding with the statuses in order,
+// repeating the last one once they run out.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+
+	status := r.statuses[0]
+	if len(r.statuses) > 1 {
+		r.statuses = r.statuses[1:]
+	}
+
+	w.WriteHeader(status)
+}
+
+func (r *receiver) attempts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, *httptest.Server) {
+	t.Helper()
+
+	r := &receiver{statuses: statuses}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return r, server
+}
+
+func readDeadLetters(t *testing.T, path string) []DeadLetter {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		var letter DeadLetter
+		if err = json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			t.Fatal(err)
+		}
+
+		letters = append(letters, letter)
+	}
+
+	return letters
+}
+
+// send delivers the event to the webhook, closing the sender once delivered or
+// dead lettered, and returns the dead letters written.
+func send(t *testing.T, webhook Webhook, events ...desk.Event) []DeadLetter {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	sender := newSender([]Webhook{webhook}, path, time.Millisecond)
+
+	for _, event := range events {
+		sender.Handle(event)
+	}
+
+	sender.Close(context.Background())
+	return readDeadLetters(t, path)
+}
+
+var targetReached = desk.Event{Type: desk.EventTargetReached, Time: time.Now(), Height: 1.1}
+
+func TestSignature(t *testing.T) {
+	r, server := newReceiver(t, http.StatusOK)
+
+	if letters := send(t, Webhook{URL: server.URL, Secret: "secret"}, targetReached); len(letters) != 0 {
+		t.Fatalf("expected the event to be delivered, got %+v", letters)
+	}
+
+	if r.attempts() != 1 {
+		t.Fatalf("expected a single delivery, got %d", r.attempts())
+	}
+
+	request, body := r.requests[0], r.bodies[0]
+
+	if signature := request.Header.Get("X-Desk-Signature"); signature != "sha256="+Sign("secret", body) {
+		t.Errorf("expected the signature of the body, got %q", signature)
+	}
+
+	if event := request.Header.Get("X-Desk-Event"); event != string(desk.EventTargetReached) {
+		t.Errorf("expected the event type header, got %q", event)
+	}
+
+	var event desk.Event
+	if err := json.Unmarshal(body, &event); err != nil || event.Type != desk.EventTargetReached || event.Height != 1.1 {
+		t.Errorf("expected the event as the body, got %s (%v)", body, err)
+	}
+}
+
+func TestUnsigned(t *testing.T) {
+	r, server := newReceiver(t, http.StatusNoContent)
+	send(t, Webhook{URL: server.URL}, targetReached)
+
+	if r.attempts() != 1 || r.requests[0].Header.Get("X-Desk-Signature") != "" {
+		t.Errorf("expected a single unsigned delivery")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+		dead     bool
+	}{
+		{"server error", []int{http.StatusBadGateway, http.StatusInternalServerError, http.StatusOK}, 3, false},
+		{"rate limited", []int{http.StatusTooManyRequests, http.StatusOK}, 2, false},
+		{"client error", []int{http.StatusBadRequest, http.StatusOK}, 1, true},
+		{"not found", []int{http.StatusNotFound}, 1, true},
+		{"always failing", []int{http.StatusServiceUnavailable}, MaxAttempts, true},
+	}
+
+	for _, test := range tests {
+		r, server := newReceiver(t, test.statuses...)
+		letters := send(t, Webhook{URL: server.URL}, targetReached)
+
+		if r.attempts() != test.attempts {
+			t.Errorf("%s: expected %d attempts, got %d", test.name, test.attempts, r.attempts())
+		}
+
+		if (len(letters) == 1) != test.dead {
+			t.Errorf("%s: expected dead lettered %v, got %+v", test.name, test.dead, letters)
+			continue
+		}
+
+		if test.dead && (letters[0].URL != server.URL || letters[0].Attempts != test.attempts ||
+			letters[0].Event.Type != desk.EventTargetReached || letters[0].Error == "") {
+			t.Errorf("%s: unexpected dead letter %+v", test.name, letters[0])
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	r, server := newReceiver(t, http.StatusOK)
+	moveStarted := desk.Event{Type: desk.EventMoveStarted, Time: time.Now()}
+
+	send(t, Webhook{URL: server.URL, Events: []desk.EventType{desk.EventMoveStarted}}, targetReached, moveStarted)
+
+	if r.attempts() != 1 || r.requests[0].Header.Get("X-Desk-Event") != string(desk.EventMoveStarted) {
+		t.Errorf("expected only the subscribed event to be delivered")
+	}
+}
+
+func TestCloseDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	sender := newSender([]Webhook{{URL: server.URL}}, path, time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		sender.Handle(targetReached)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	sender.Close(ctx)
+
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected Close to stop waiting at the deadline, took %s", elapsed)
+	}
+
+	letters := readDeadLetters(t, path)
+	if len(letters) != 3 {
+		t.Fatalf("expected the in flight and queued events to be dead lettered, got %+v", letters)
+	}
+
+	if letters[0].Attempts != 1 || letters[1].Attempts != 0 || letters[2].Attempts != 0 {
+		t.Errorf("expected only the event in flight to be attempted, got %+v", letters)
+	}
+}
+
+func TestHandleAfterClose(t *testing.T) {
+	r, server := newReceiver(t, http.StatusOK)
+
+	sender := newSender([]Webhook{{URL: server.URL}}, "", time.Millisecond)
+	sender.Close(context.Background())
+	sender.Handle(targetReached)
+
+	if r.attempts() != 0 {
+		t.Errorf("expected no delivery once closed, got %d", r.attempts())
+	}
+}